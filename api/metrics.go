@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"gopher-dish/object"
+)
+
+const metricsPrefix = "gopherdish_"
+
+var deathCauseNames = map[object.DeathCause]string{
+	object.DEATH_STARVATION: "starvation",
+	object.DEATH_BITE:       "bite",
+	object.DEATH_WOUNDS:     "wounds",
+	object.DEATH_EVENT:      "event",
+	object.DEATH_EDIT:       "edit",
+}
+
+// Prometheus text exposition of the world state
+func (s *Server) handleMetrics(rw http.ResponseWriter, r *http.Request) {
+	w := s.world
+	w.PlacesDrawMux.Lock()
+	var living int
+	var energy uint64
+	for _, obj := range w.Objects {
+		if lively, ok := obj.(object.Lively); ok && !lively.IsDied() {
+			living++
+			energy += uint64(lively.GetEnergy())
+		}
+	}
+	var meanEnergy float64
+	if living > 0 {
+		meanEnergy = float64(energy) / float64(living)
+	}
+	var deaths [object.DEATH_CAUSE_ENUM_SIZE]uint64
+	for cause := range deaths {
+		deaths[cause] = w.Metrics.Deaths(object.DeathCause(cause))
+	}
+
+	ticks, year, epoch, framerate, objects := w.Ticks, w.Year, w.Epoch, w.Framerate, len(w.Objects)
+	paused := 0
+	if w.Paused {
+		paused = 1
+	}
+	phases := map[string]float64{
+		"prepare": w.Metrics.PrepareDuration.Seconds(),
+		"handle":  w.Metrics.HandleDuration.Seconds(),
+		"removal": w.Metrics.RemovalDuration.Seconds(),
+	}
+	births := w.Metrics.Births()
+	w.PlacesDrawMux.Unlock()
+
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetric(rw, "ticks_total", "counter", "World ticks since the world was created.", float64(ticks))
+	writeMetric(rw, "year", "gauge", "Current world year.", float64(year))
+	writeMetric(rw, "epoch", "gauge", "Current world epoch.", float64(epoch))
+	writeMetric(rw, "tick_rate", "gauge", "Ticks per second measured over the last tick.", float64(framerate))
+	writeMetric(rw, "paused", "gauge", "Whether the world is paused.", float64(paused))
+	writeMetric(rw, "population", "gauge", "Living cells.", float64(living))
+	writeMetric(rw, "objects", "gauge", "Objects in the world map, the dead ones included.", float64(objects))
+	writeMetric(rw, "mean_energy", "gauge", "Mean energy of the living cells.", meanEnergy)
+	writeMetric(rw, "births_total", "counter", "Cells born since the start of the process.", float64(births))
+
+	writeHeader(rw, "deaths_total", "counter", "Cells died since the start of the process by cause.")
+	for cause, count := range deaths {
+		fmt.Fprintf(rw, "%sdeaths_total{cause=%q} %d\n", metricsPrefix, deathCauseNames[object.DeathCause(cause)], count)
+	}
+
+	writeHeader(rw, "tick_phase_seconds", "gauge", "Duration of the phases of the last tick.")
+	for _, phase := range []string{"prepare", "handle", "removal"} {
+		fmt.Fprintf(rw, "%stick_phase_seconds{phase=%q} %g\n", metricsPrefix, phase, phases[phase])
+	}
+}
+
+func writeMetric(out io.Writer, name, kind, help string, value float64) {
+	writeHeader(out, name, kind, help)
+	fmt.Fprintf(out, "%s%s %g\n", metricsPrefix, name, value)
+}
+
+func writeHeader(out io.Writer, name, kind, help string) {
+	fmt.Fprintf(out, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+}
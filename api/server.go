@@ -0,0 +1,413 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/utils/genasm"
+	"gopher-dish/utils/genbank"
+	"gopher-dish/world"
+	"gopher-dish/world/worldsaver"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+	DefaultSaveName = "world.gdw"
+	// Largest genome file accepted by the inject endpoint
+	MaxInjectSize = 1 << 20
+)
+
+//go:embed viewer/index.html
+var viewerPage []byte
+
+// HTTP/JSON control and query API of a running world,
+// every handler holds the world lock while it touches the world
+type Server struct {
+	// Ticks between the frames of the websocket stream
+	StreamEvery uint64
+	// Directory of the saves, clients only name the files in it
+	SaveDir string
+
+	world *world.World
+	mux   *http.ServeMux
+}
+
+func New(w *world.World) *Server {
+	s := &Server{StreamEvery: DefaultStreamEvery, SaveDir: ".", world: w, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("/", s.get(s.handleViewer))
+	s.mux.HandleFunc("/metrics", s.get(s.handleMetrics))
+	s.mux.HandleFunc("/api/stream", s.get(s.handleStream))
+	s.mux.HandleFunc("/api/world", s.get(s.handleWorld))
+	s.mux.HandleFunc("/api/objects", s.get(s.handleObjects))
+	s.mux.HandleFunc("/api/objects/", s.get(s.handleObject))
+	s.mux.HandleFunc("/api/place", s.get(s.handlePlace))
+	s.mux.HandleFunc("/api/pause", s.post(s.handlePause))
+	s.mux.HandleFunc("/api/resume", s.post(s.handleResume))
+	s.mux.HandleFunc("/api/tick-period", s.post(s.handleTickPeriod))
+	s.mux.HandleFunc("/api/save", s.post(s.handleSave))
+	s.mux.HandleFunc("/api/inject", s.post(s.handleInject))
+	return s
+}
+
+func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(rw, r)
+}
+
+// Routes for the endpoints of the other packages, like the frame stream
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s)
+}
+
+type WorldInfo struct {
+	Width, Height uint32
+	Tick          uint64
+	Year          uint64
+	Epoch         uint64
+	IdCounter     uint64
+	Population    int
+	Paused        bool
+}
+
+type ObjectInfo struct {
+	Id         uint64
+	Kind       string
+	Position   object.Position
+	Energy     uint32
+	Health     uint32
+	Generation uint64
+	Hash       uint64
+	Died       bool
+}
+
+type ObjectsPage struct {
+	Total   int
+	Offset  int
+	Objects []ObjectInfo
+}
+
+type GenomeInfo struct {
+	Hash        uint64
+	Traits      cell.Traits
+	Code        string
+	Disassembly string
+}
+
+type CellState struct {
+	ObjectInfo
+	Age            uint32
+	Weight         byte
+	Killed         bool
+	Picked         bool
+	Rotation       int32
+	ParentsChain   object.ParentsChain
+	Bonds          []uint64
+	Diet           object.Diet
+	BagageSelected uint32
+	BagageFullness uint32
+	Brain          cell.Brain
+	Genome         GenomeInfo
+}
+
+// Browser viewer of the frame stream
+func (s *Server) handleViewer(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(rw, http.StatusNotFound, "not found")
+		return
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.Write(viewerPage)
+}
+
+func (s *Server) handleWorld(rw http.ResponseWriter, r *http.Request) {
+	w := s.world
+	w.PlacesDrawMux.Lock()
+	info := WorldInfo{
+		Width:      w.Width,
+		Height:     w.Height,
+		Tick:       w.Ticks,
+		Year:       w.Year,
+		Epoch:      w.Epoch,
+		IdCounter:  w.ObjectsIdCounter,
+		Population: len(w.Objects),
+		Paused:     w.Paused,
+	}
+	w.PlacesDrawMux.Unlock()
+
+	writeJSON(rw, http.StatusOK, info)
+}
+
+// Objects ordered by ID, paged by the offset and limit query parameters
+func (s *Server) handleObjects(rw http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(rw, http.StatusBadRequest, "invalid offset")
+		return
+	}
+	limit, err := queryInt(r, "limit", DefaultPageSize)
+	if err != nil || limit < 1 || limit > MaxPageSize {
+		writeError(rw, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+		return
+	}
+
+	w := s.world
+	w.PlacesDrawMux.Lock()
+	ids := make([]uint64, 0, len(w.Objects))
+	for id := range w.Objects {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	page := ObjectsPage{Total: len(ids), Offset: offset, Objects: []ObjectInfo{}}
+	for i := offset; i < len(ids) && i < offset+limit; i++ {
+		page.Objects = append(page.Objects, objectInfo(w.Objects[ids[i]]))
+	}
+	w.PlacesDrawMux.Unlock()
+
+	writeJSON(rw, http.StatusOK, page)
+}
+
+func (s *Server) handleObject(rw http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/api/objects/"), 10, 64)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "invalid object id")
+		return
+	}
+
+	w := s.world
+	w.PlacesDrawMux.Lock()
+	state := objectState(w.GetObject(id))
+	w.PlacesDrawMux.Unlock()
+
+	if state == nil {
+		writeError(rw, http.StatusNotFound, fmt.Sprintf("there is no object with id %d", id))
+		return
+	}
+	writeJSON(rw, http.StatusOK, state)
+}
+
+// Object at the x and y query parameters
+func (s *Server) handlePlace(rw http.ResponseWriter, r *http.Request) {
+	x, errX := queryInt(r, "x", -1)
+	y, errY := queryInt(r, "y", -1)
+	if errX != nil || errY != nil || x < 0 || y < 0 || x >= int(s.world.Width) || y >= int(s.world.Height) {
+		writeError(rw, http.StatusBadRequest, "invalid position")
+		return
+	}
+
+	w := s.world
+	w.PlacesDrawMux.Lock()
+	state := objectState(w.Places[x][y])
+	w.PlacesDrawMux.Unlock()
+
+	if state == nil {
+		writeError(rw, http.StatusNotFound, fmt.Sprintf("there is no object at [%d, %d]", x, y))
+		return
+	}
+	writeJSON(rw, http.StatusOK, state)
+}
+
+func (s *Server) handlePause(rw http.ResponseWriter, r *http.Request) {
+	s.setPaused(true)
+	s.handleWorld(rw, r)
+}
+
+func (s *Server) handleResume(rw http.ResponseWriter, r *http.Request) {
+	s.setPaused(false)
+	s.handleWorld(rw, r)
+}
+
+// Body is {"Period": "20ms"}
+func (s *Server) handleTickPeriod(rw http.ResponseWriter, r *http.Request) {
+	var req struct{ Period string }
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	period, err := time.ParseDuration(req.Period)
+	if err != nil || period <= 0 {
+		writeError(rw, http.StatusBadRequest, "period must be a positive duration")
+		return
+	}
+
+	s.world.PlacesDrawMux.Lock()
+	s.world.SetTickPeriod(period)
+	s.world.PlacesDrawMux.Unlock()
+
+	writeJSON(rw, http.StatusOK, req)
+}
+
+// Body is {"Name": "world.gdw"}, the name is optional. It is a bare file name
+// in the save directory, so clients can't write anywhere else
+func (s *Server) handleSave(rw http.ResponseWriter, r *http.Request) {
+	var req struct{ Name string }
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.Name == "" {
+		req.Name = DefaultSaveName
+	}
+	if req.Name != filepath.Base(req.Name) || req.Name == "." || req.Name == ".." || strings.ContainsAny(req.Name, `/\`) {
+		writeError(rw, http.StatusBadRequest, fmt.Sprintf("save name %q must be a bare file name", req.Name))
+		return
+	}
+
+	if err := worldsaver.SaveFile(s.world, filepath.Join(s.SaveDir, req.Name)); err != nil {
+		writeError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, req)
+}
+
+// Body is a genome file of any format, positions are in the "at" query
+// parameter as "x:y,x:y", genomes are placed round-robin.
+// Positions out of the world are rejected before anything is placed
+func (s *Server) handleInject(rw http.ResponseWriter, r *http.Request) {
+	positions, err := genbank.ParsePositions(r.URL.Query().Get("at"), int32(s.world.Width), int32(s.world.Height))
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	entries, err := genbank.Read(http.MaxBytesReader(rw, r.Body, MaxInjectSize))
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		writeError(rw, http.StatusBadRequest, "no genomes in the request body")
+		return
+	}
+
+	var result struct {
+		Injected []uint64
+		Failed   []object.Position
+	}
+	for n, pos := range positions {
+		if c, err := genbank.Inject(s.world, entries[n%len(entries)], pos); err == nil {
+			result.Injected = append(result.Injected, c.GetID())
+		} else {
+			result.Failed = append(result.Failed, pos)
+		}
+	}
+
+	writeJSON(rw, http.StatusOK, result)
+}
+
+func (s *Server) setPaused(paused bool) {
+	s.world.PlacesDrawMux.Lock()
+	s.world.Paused = paused
+	s.world.PlacesDrawMux.Unlock()
+}
+
+func (s *Server) get(handler http.HandlerFunc) http.HandlerFunc {
+	return method(http.MethodGet, handler)
+}
+
+func (s *Server) post(handler http.HandlerFunc) http.HandlerFunc {
+	return method(http.MethodPost, handler)
+}
+
+func method(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != name {
+			rw.Header().Set("Allow", name)
+			writeError(rw, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handler(rw, r)
+	}
+}
+
+// Full state of the cell, short info of the other objects, nil if there is no object
+func objectState(obj object.Movable) any {
+	if c, ok := obj.(*cell.Cell); ok {
+		return cellState(c)
+	} else if obj != nil {
+		return objectInfo(obj)
+	}
+	return nil
+}
+
+func objectInfo(obj object.Movable) ObjectInfo {
+	info := ObjectInfo{Id: obj.GetID(), Kind: "object", Position: obj.GetPosition(), Energy: obj.GetEnergy()}
+	if _, ok := obj.(object.Pickable); ok {
+		info.Kind = "pickable"
+	}
+	if lively, ok := obj.(object.Lively); ok {
+		info.Kind = "lively"
+		info.Health = lively.GetHealth()
+		info.Generation = lively.GetGeneration()
+		info.Hash = lively.GetGenomeHash()
+		info.Died = lively.IsDied()
+	}
+	if _, ok := obj.(*cell.Cell); ok {
+		info.Kind = "cell"
+	}
+	return info
+}
+
+func cellState(c *cell.Cell) CellState {
+	return CellState{
+		ObjectInfo:     objectInfo(c),
+		Age:            c.Age,
+		Weight:         c.Weight,
+		Killed:         c.Killed,
+		Picked:         c.Picked,
+		Rotation:       c.Rotation.Degree,
+		ParentsChain:   c.ParentsChain,
+		Bonds:          c.GetBonds(),
+		Diet:           c.Diet,
+		BagageSelected: c.BagageSelected,
+		BagageFullness: c.BagageFullness,
+		Brain:          c.Brain,
+		Genome: GenomeInfo{
+			Hash:        c.Genome.Hash,
+			Traits:      c.Genome.Traits,
+			Code:        genomeHex(c.Genome),
+			Disassembly: genasm.Disassemble(c.Genome),
+		},
+	}
+}
+
+func genomeHex(g cell.Genome) string {
+	code := make([]byte, len(g.Code))
+	g.Read(code)
+	return hex.EncodeToString(code)
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, status int, message string) {
+	writeJSON(rw, status, struct{ Error string }{message})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopher-dish/cell"
+	"gopher-dish/utils/genbank"
+	"gopher-dish/world"
+)
+
+func TestHandleSave(t *testing.T) {
+	tests := []struct {
+		body   string
+		status int
+		// file expected in the save directory
+		file string
+	}{
+		{"", http.StatusOK, DefaultSaveName},
+		{`{"Name": "snapshot.gdw.gz"}`, http.StatusOK, "snapshot.gdw.gz"},
+		{`{"Name": "../escape.gdw"}`, http.StatusBadRequest, ""},
+		{`{"Name": "/tmp/escape.gdw"}`, http.StatusBadRequest, ""},
+		{`{"Name": "sub/escape.gdw"}`, http.StatusBadRequest, ""},
+		{`{"Name": "sub\\escape.gdw"}`, http.StatusBadRequest, ""},
+		{`{"Name": ".."}`, http.StatusBadRequest, ""},
+		{`{"Path": "/tmp/escape.gdw"}`, http.StatusOK, DefaultSaveName},
+	}
+
+	for _, test := range tests {
+		t.Run(test.body, func(t *testing.T) {
+			parent := t.TempDir()
+			s := New(world.New(8, 8, time.Millisecond))
+			s.SaveDir = filepath.Join(parent, "saves")
+			if err := os.Mkdir(s.SaveDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/save", strings.NewReader(test.body))
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, test.status, rec.Body)
+			}
+
+			saved, _ := filepath.Glob(filepath.Join(parent, "*", "*"))
+			outside, _ := filepath.Glob(filepath.Join(parent, "*.gdw"))
+			if len(outside) != 0 {
+				t.Errorf("saved outside of the save directory: %v", outside)
+			}
+			if test.file == "" {
+				if len(saved) != 0 {
+					t.Errorf("saved %v, want nothing", saved)
+				}
+				return
+			}
+			if len(saved) != 1 || saved[0] != filepath.Join(s.SaveDir, test.file) {
+				t.Errorf("saved %v, want %s", saved, test.file)
+			}
+		})
+	}
+}
+
+func TestHandleInject(t *testing.T) {
+	tests := []struct {
+		at     string
+		status int
+		// cells injected and positions failed
+		injected, failed int
+	}{
+		{"1:1,7:7", http.StatusOK, 2, 0},
+		{"1:1,1:1", http.StatusOK, 1, 1},
+		{"8:1", http.StatusBadRequest, 0, 0},
+		{"1:1,1:8", http.StatusBadRequest, 0, 0},
+		{"-1:1", http.StatusBadRequest, 0, 0},
+		{"1:-1", http.StatusBadRequest, 0, 0},
+	}
+
+	var body bytes.Buffer
+	entries := []genbank.Entry{{Genome: cell.CreateBaseGenome()}}
+	if err := genbank.Write(&body, entries, genbank.FORMAT_TEXT); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.at, func(t *testing.T) {
+			w := world.New(8, 8, time.Millisecond)
+			s := New(w)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/inject?at="+test.at, bytes.NewReader(body.Bytes()))
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, test.status, rec.Body)
+			}
+			if len(w.Objects) != test.injected {
+				t.Errorf("%d objects in the world, want %d", len(w.Objects), test.injected)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var result struct {
+				Injected []uint64
+				Failed   []json.RawMessage
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Injected) != test.injected || len(result.Failed) != test.failed {
+				t.Errorf("injected %v, failed %d", result.Injected, len(result.Failed))
+			}
+		})
+	}
+}
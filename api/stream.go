@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gopher-dish/object"
+	"gopher-dish/render"
+)
+
+const (
+	DefaultStreamEvery = 4
+	StreamPollInterval = 33 * time.Millisecond
+	// Kind, filter, width, height and tick
+	streamHeaderSize = 1 + 1 + 4 + 4 + 8
+)
+
+// Stream frame kinds list
+const (
+	// RGB of every square of the fields
+	FRAME_FIELD = iota
+	// Occupancy bitmap, then RGB of every occupied square
+	FRAME_KEY
+	// Count of the changed squares, then index and RGBA of each, zero alpha is empty
+	FRAME_DELTA
+)
+
+// Messages from the viewer, only the filter is supported
+type streamRequest struct {
+	Filter string
+}
+
+// Colour grid of the objects in rows from the top, zero is an empty square
+type streamGrid []uint32
+
+// Streams the world grid every StreamEvery ticks over the websocket,
+// every connection has its own filter and the previous grid for the deltas
+func (s *Server) handleStream(rw http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebsocket(rw, r)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer ws.Close()
+
+	filters := make(chan render.Filter, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, payload, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req streamRequest
+			if json.Unmarshal(payload, &req) != nil {
+				continue
+			}
+			if filter, ok := render.FilterByName(req.Filter); ok {
+				select {
+				case <-filters:
+				default:
+				}
+				filters <- filter
+			}
+		}
+	}()
+
+	var (
+		palette  render.Palette
+		previous streamGrid
+		lastTick uint64
+		revision uint64
+	)
+	ticker := time.NewTicker(StreamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case palette.Filter = <-filters:
+			previous = nil
+		case <-ticker.C:
+		}
+
+		w := s.world
+		w.PlacesDrawMux.Lock()
+		if previous != nil && w.Ticks-lastTick < s.StreamEvery {
+			w.PlacesDrawMux.Unlock()
+			continue
+		}
+
+		var field []byte
+		if previous == nil || w.EnvironmentRevision != revision {
+			revision = w.EnvironmentRevision
+			field = s.streamField()
+		}
+		grid := make(streamGrid, w.Width*w.Height)
+		palette.Measure(w)
+		for _, o := range w.Objects {
+			pos := o.GetPosition()
+			if pos.X < 0 || pos.X >= int32(w.Width) || pos.Y < 0 || pos.Y >= int32(w.Height) {
+				continue
+			}
+			c := palette.ObjectRGBA(o)
+			grid[s.streamIndex(pos)] = uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | 0xFF
+		}
+		header := s.streamHeader(palette.Filter, w.Ticks)
+		lastTick = w.Ticks
+		w.PlacesDrawMux.Unlock()
+
+		if field != nil {
+			header[0] = FRAME_FIELD
+			if ws.WriteMessage(WS_OPCODE_BINARY, append(append([]byte(nil), header...), field...)) != nil {
+				return
+			}
+		}
+		if ws.WriteMessage(WS_OPCODE_BINARY, encodeGrid(header, grid, previous)) != nil {
+			return
+		}
+		previous = grid
+	}
+}
+
+// Called under the world lock
+func (s *Server) streamField() []byte {
+	w := s.world
+	field := make([]byte, 0, w.Width*w.Height*3)
+	for y := int32(0); y < int32(w.Height); y++ {
+		for x := int32(0); x < int32(w.Width); x++ {
+			c := render.FieldRGBA(w, object.Position{X: x, Y: y})
+			field = append(field, c.R, c.G, c.B)
+		}
+	}
+	return field
+}
+
+func (s *Server) streamIndex(pos object.Position) int {
+	return int(pos.Y)*int(s.world.Width) + int(pos.X)
+}
+
+func (s *Server) streamHeader(filter render.Filter, tick uint64) []byte {
+	header := make([]byte, streamHeaderSize)
+	header[1] = byte(filter)
+	binary.LittleEndian.PutUint32(header[2:], s.world.Width)
+	binary.LittleEndian.PutUint32(header[6:], s.world.Height)
+	binary.LittleEndian.PutUint64(header[10:], tick)
+	return header
+}
+
+// Delta to the previous grid, or the key frame if there is no previous grid or it is smaller
+func encodeGrid(header []byte, grid, previous streamGrid) []byte {
+	occupied := 0
+	for _, c := range grid {
+		if c != 0 {
+			occupied++
+		}
+	}
+	keySize := (len(grid)+7)/8 + occupied*3
+
+	if len(previous) == len(grid) {
+		changed := 0
+		for i := range grid {
+			if grid[i] != previous[i] {
+				changed++
+			}
+		}
+		if 4+changed*8 < keySize {
+			frame := append(append(make([]byte, 0, len(header)+4+changed*8), header...), 0, 0, 0, 0)
+			frame[0] = FRAME_DELTA
+			binary.LittleEndian.PutUint32(frame[len(header):], uint32(changed))
+			for i := range grid {
+				if grid[i] != previous[i] {
+					c := grid[i]
+					frame = append(frame, byte(i), byte(i>>8), byte(i>>16), byte(i>>24))
+					frame = append(frame, byte(c>>24), byte(c>>16), byte(c>>8), byte(c))
+				}
+			}
+			return frame
+		}
+	}
+
+	frame := append(make([]byte, 0, len(header)+keySize), header...)
+	frame[0] = FRAME_KEY
+	bitmap := make([]byte, (len(grid)+7)/8)
+	for i, c := range grid {
+		if c != 0 {
+			bitmap[i/8] |= 1 << (i % 8)
+		}
+	}
+	frame = append(frame, bitmap...)
+	for _, c := range grid {
+		if c != 0 {
+			frame = append(frame, byte(c>>24), byte(c>>16), byte(c>>8))
+		}
+	}
+	return frame
+}
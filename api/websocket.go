@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Largest message accepted from the client
+	WebsocketMaxMessage = 4096
+	WebsocketWriteLimit = 5 * time.Second
+
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// Frame opcodes list
+const (
+	WS_OPCODE_CONTINUATION = 0x0
+	WS_OPCODE_TEXT         = 0x1
+	WS_OPCODE_BINARY       = 0x2
+	WS_OPCODE_CLOSE        = 0x8
+	WS_OPCODE_PING         = 0x9
+	WS_OPCODE_PONG         = 0xA
+)
+
+var errWebsocketProtocol = errors.New("websocket protocol error")
+
+// Minimal RFC 6455 server connection, messages are not fragmented
+type websocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMux sync.Mutex
+}
+
+func upgradeWebsocket(rw http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("not a websocket handshake")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if key == "" {
+		return nil, errors.New("missing websocket key")
+	}
+
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection can't be hijacked")
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	_, err = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &websocketConn{conn: conn, reader: buffered.Reader}, nil
+}
+
+func (c *websocketConn) WriteMessage(opcode byte, payload []byte) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+
+	header := make([]byte, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+		header = header[:2]
+	case n <= 0xFFFF:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+		header = header[:4]
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(WebsocketWriteLimit))
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// Next text or binary message, pings are answered and close ends the reading with io.EOF
+func (c *websocketConn) ReadMessage() (opcode byte, payload []byte, err error) {
+	for {
+		var header [2]byte
+		if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+			return
+		}
+		opcode = header[0] & 0x0F
+		// client frames are always masked and we don't expect fragments
+		if header[0]&0x80 == 0 || header[1]&0x80 == 0 || opcode == WS_OPCODE_CONTINUATION {
+			return 0, nil, errWebsocketProtocol
+		}
+
+		size := uint64(header[1] & 0x7F)
+		switch size {
+		case 126:
+			var ext [2]byte
+			if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+				return
+			}
+			size = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+				return
+			}
+			size = binary.BigEndian.Uint64(ext[:])
+		}
+		if size > WebsocketMaxMessage {
+			return 0, nil, errWebsocketProtocol
+		}
+
+		var mask [4]byte
+		if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+			return
+		}
+		payload = make([]byte, size)
+		if _, err = io.ReadFull(c.reader, payload); err != nil {
+			return
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case WS_OPCODE_PING:
+			if err = c.WriteMessage(WS_OPCODE_PONG, payload); err != nil {
+				return
+			}
+		case WS_OPCODE_PONG:
+		case WS_OPCODE_CLOSE:
+			c.WriteMessage(WS_OPCODE_CLOSE, nil)
+			return 0, nil, io.EOF
+		default:
+			return
+		}
+	}
+}
+
+func (c *websocketConn) Close() error {
+	return c.conn.Close()
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
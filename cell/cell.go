@@ -3,6 +3,7 @@ package cell
 import (
 	"gopher-dish/object"
 	"gopher-dish/world"
+	"math"
 )
 
 const (
@@ -12,6 +13,8 @@ const (
 	RegistersCount = 4
 	SensorsCount   = 4
 	BagageSize     = 4
+	BondsCount     = 8
+	MaxColonySize  = 64
 )
 
 const (
@@ -23,6 +26,10 @@ const (
 	BaseBiteStrength        = 40
 	AgeInfluenceMultiplier  = 0.2
 	BaseReproduceEnergyCost = 32
+	BaseBondEnergyCost      = 4
+	BaseSignalEnergyCost    = 1
+	BaseLookEnergyCost      = 2
+	BondShareDivider        = 4
 )
 
 // Registers list
@@ -70,8 +77,8 @@ type Cell struct {
 	ParentsChain object.ParentsChain
 
 	Age    uint32
-	Health byte
-	Energy byte
+	Health uint32
+	Energy uint32
 	Weight byte
 
 	Died   bool
@@ -86,6 +93,11 @@ type Cell struct {
 	BagageSelected uint32
 	BagageFullness uint32
 
+	Bonds [BondsCount]uint64
+
+	// Not saved, counted from the world load
+	Diet object.Diet
+
 	World    *world.World
 	Position object.Position
 	Rotation object.Rotation
@@ -97,8 +109,8 @@ type saveCellDescriptor struct {
 	ParentsChain object.ParentsChain
 
 	Age    uint32
-	Health byte
-	Energy byte
+	Health uint32
+	Energy uint32
 	Weight byte
 
 	Died   bool
@@ -111,12 +123,14 @@ type saveCellDescriptor struct {
 	BagageSelected uint32
 	BagageFullness uint32
 
+	Bonds [BondsCount]uint64
+
 	Position object.Position
 	Rotation object.Rotation
 }
 
 func New(w *world.World, parent *Cell, pos object.Position) *Cell {
-	c := &Cell{Health: BaseHealth, Energy: BaseEnergy, Weight: BaseWeight, World: w}
+	c := &Cell{Health: BaseHealth, Energy: BaseEnergy, World: w}
 
 	if parent != nil {
 		for i := 0; i < object.RelatedDepth-1; i++ {
@@ -124,7 +138,7 @@ func New(w *world.World, parent *Cell, pos object.Position) *Cell {
 		}
 		c.ParentsChain[0] = parent.Name
 		c.Generation = parent.Generation + 1
-		c.Genome = parent.Genome.Mutate()
+		c.Genome = parent.Genome.MutateN(GenomeMutationRate + w.MutationBoost)
 		if c.Energy > parent.Energy {
 			c.Energy = parent.Energy
 		}
@@ -132,10 +146,14 @@ func New(w *world.World, parent *Cell, pos object.Position) *Cell {
 		c.Genome = CreateBaseGenome()
 	}
 
+	c.Weight = c.Genome.Traits[TRAIT_WEIGHT]
 	c.Position = pos
 	c.Name = w.ReserveID()
 
 	if c.Name > 0 && w.PlaceObject(c, c.Position) {
+		if parent != nil {
+			w.Metrics.Birth()
+		}
 		return c
 	} else {
 		return nil
@@ -153,13 +171,14 @@ func (c *Cell) incCounter() uint64 {
 func (c *Cell) recycle(rType uint64) bool {
 	switch rType {
 	case RCL_SUNENERGY:
-		c.IncreaseEnergy(c.World.GetSunlightAtPosition(c.Position))
+		energy := uint32(c.World.GetSunlightAtPosition(c.Position)) * uint32(c.Genome.Traits[TRAIT_PHOTOSYNTHESIS]) / BasePhotosynthesis
+		c.eat(object.FOOD_SUNLIGHT, energy)
 		return true
 	case RCL_BAGAGE:
 		if c.Bagage[c.BagageSelected] == nil {
 			return false
 		}
-		c.IncreaseEnergy(c.Bagage[c.BagageSelected].GetEnergy())
+		c.eat(object.FOOD_BAGAGE, c.Bagage[c.BagageSelected].GetEnergy())
 		c.Bagage[c.BagageSelected] = nil
 		return true
 	default:
@@ -167,30 +186,122 @@ func (c *Cell) recycle(rType uint64) bool {
 	}
 }
 
+// Increase energy and account the food source in the diet
+// Returns the energy actually gained, the rest is over the capacity
+func (c *Cell) eat(source int, energy uint32) (gain uint32) {
+	before := c.Energy
+	c.IncreaseEnergy(energy)
+	if c.Energy > before {
+		gain = c.Energy - before
+		if c.Diet[source] > math.MaxUint32-gain {
+			c.Diet[source] = math.MaxUint32
+		} else {
+			c.Diet[source] += gain
+		}
+	}
+	return
+}
+
 func (c *Cell) getRelPos(rot object.Rotation) object.Position {
-	newPos := c.Position
+	dx, dy := c.getRelOffset(rot)
+	newPos := object.Position{X: c.Position.X + dx, Y: c.Position.Y + dy}
+	newPos.X = (newPos.X + int32(c.World.Width)) % int32(c.World.Width)
+	return newPos
+}
+
+func (c *Cell) energyCapacity() uint32 {
+	return uint32(uint64(c.World.Config.MaxEnergy) * uint64(c.Genome.Traits[TRAIT_MAXENERGY]) / 255)
+}
+
+// Byte view of the energy for the VM
+func (c *Cell) scaleEnergy(energy uint32) byte {
+	return scaleToByte(energy, c.World.Config.MaxEnergy)
+}
+
+// Byte view of the health for the VM
+func (c *Cell) scaleHealth(health uint32) byte {
+	return scaleToByte(health, c.World.Config.MaxHealth)
+}
+
+func scaleToByte(value, max uint32) byte {
+	if max == 0 {
+		return 0
+	}
+	scaled := uint64(value) * 255 / uint64(max)
+	if scaled > 255 {
+		return 255
+	}
+	return byte(scaled)
+}
+
+func (c *Cell) sightRange() int32 {
+	sight := int32(c.Genome.Traits[TRAIT_SIGHT])
+	if sight > int32(c.World.Config.LookRange) {
+		sight = int32(c.World.Config.LookRange)
+	}
+	return sight
+}
+
+func (c *Cell) getRelOffset(rot object.Rotation) (dx, dy int32) {
 	switch c.Rotation.Rotate(rot.Degree).Degree {
 	case 0:
-		newPos.Y--
+		dy--
 	case 45:
-		newPos.X++
-		newPos.Y--
+		dx++
+		dy--
 	case 90:
-		newPos.X++
+		dx++
 	case 135:
-		newPos.X++
-		newPos.Y++
+		dx++
+		dy++
 	case 180:
-		newPos.Y++
+		dy++
 	case 225:
-		newPos.X--
-		newPos.Y++
+		dx--
+		dy++
 	case 270:
-		newPos.X--
+		dx--
 	case 315:
-		newPos.X--
-		newPos.Y--
+		dx--
+		dy--
 	}
-	newPos.X = (newPos.X + int32(c.World.Width)) % int32(c.World.Width)
-	return newPos
+	return
+}
+
+// Cast a ray and find the first object or wall within the range
+func (c *Cell) look(rot object.Rotation, lookRange int32) (distance int32, objType byte) {
+	dx, dy := c.getRelOffset(rot)
+	pos := c.Position
+
+	for distance = 1; distance <= lookRange; distance++ {
+		pos.X = (pos.X + dx + int32(c.World.Width)) % int32(c.World.Width)
+		pos.Y += dy
+		if pos.Y < 0 || pos.Y >= int32(c.World.Height) {
+			return distance, OBJ_WALL
+		}
+		if pos == c.Position {
+			break
+		}
+
+		switch o := c.World.GetObjectAtPosition(pos).(type) {
+		case nil:
+			continue
+		case *Cell:
+			if o.IsDied() {
+				return distance, OBJ_DEAD
+			} else if c.IsReleated(o) {
+				return distance, OBJ_RELATED
+			}
+			return distance, OBJ_UNRELATED
+		case object.Lively:
+			if o.IsDied() {
+				return distance, OBJ_DEAD
+			}
+			return distance, OBJ_BODY
+		default:
+			return distance, OBJ_BODY
+		}
+	}
+
+	return 0, OBJ_EMPTY
 }
@@ -2,6 +2,7 @@ package cell
 
 import (
 	"gopher-dish/object"
+	"gopher-dish/world"
 	"math"
 	"math/rand"
 )
@@ -58,6 +59,19 @@ const (
 	CMD_GETHEALTH  // + get self health
 	CMD_GETENERGY  // + get self energy
 	CMD_GETCOUNTER // + get current command counter
+	// Colony commands
+	CMD_BOND      // + bond with near related cell
+	CMD_UNBOND    // + break bond with near cell
+	CMD_CHECKBOND // + check if there is a bond with near cell
+	CMD_BONDMASK  // + get directions of all bonds as a bit mask
+	// Signal commands
+	CMD_EMIT     // + emit signal at own square
+	CMD_SMELL    // + get signal concentration at own square
+	CMD_SMELLDIR // + get direction to the highest signal concentration
+	// Vision commands
+	CMD_LOOK // + get distance and type of the first object in direction
+	// Body commands
+	CMD_GETTRAIT // + get value of body trait
 
 	CMD_ENUM_SIZE
 )
@@ -140,7 +154,9 @@ var commandMap = map[Command]CommandDescriptor{
 		cond := truncCmd(c.Genome.Code[c.incCounter()], CND_ENUM_SIZE)
 		pos := truncCmd(c.Genome.Code[c.incCounter()], GenomeLength)
 
-		if cond == CND_NONE || cond&uint64(c.Brain.CompareFlag) > 0 {
+		taken := cond == CND_NONE || cond&uint64(c.Brain.CompareFlag) > 0
+		c.traceBranch(CMD_JMP, taken)
+		if taken {
 			c.Brain.CommandCounter = pos
 		} else {
 			c.incCounter()
@@ -152,6 +168,7 @@ var commandMap = map[Command]CommandDescriptor{
 		pos := truncCmd(c.Genome.Code[c.incCounter()], GenomeLength)
 
 		if c.Brain.StackCounter >= StackDepth {
+			c.traceBranch(CMD_DIVE, false)
 			c.incCounter()
 			return
 		}
@@ -165,7 +182,9 @@ var commandMap = map[Command]CommandDescriptor{
 		c.Brain.Stack[cnt].JumpCompareFlag = c.Brain.CompareFlag
 		c.Brain.StackCounter++
 
-		if cond == CND_NONE || cond&uint64(c.Brain.CompareFlag) > 0 {
+		taken := cond == CND_NONE || cond&uint64(c.Brain.CompareFlag) > 0
+		c.traceBranch(CMD_DIVE, taken)
+		if taken {
 			c.Brain.CommandCounter = pos
 		} else {
 			c.incCounter()
@@ -176,12 +195,15 @@ var commandMap = map[Command]CommandDescriptor{
 		cond := truncCmd(c.Genome.Code[c.incCounter()], CND_ENUM_SIZE)
 
 		if c.Brain.StackCounter == 0 {
+			c.traceBranch(CMD_LIFT, false)
 			c.incCounter()
 			return
 		}
 		c.Brain.StackCounter--
 
-		if cond == CND_NONE || cond&uint64(c.Brain.CompareFlag) > 0 {
+		taken := cond == CND_NONE || cond&uint64(c.Brain.CompareFlag) > 0
+		c.traceBranch(CMD_LIFT, taken)
+		if taken {
 			cnt := c.Brain.StackCounter
 			c.Brain.CompareFlag = c.Brain.Stack[cnt].JumpCompareFlag
 			c.Brain.Registers = c.Brain.Stack[cnt].JumpRegisters
@@ -384,12 +406,8 @@ var commandMap = map[Command]CommandDescriptor{
 			return
 		}
 
-		biteStrength := int(math.Round(BaseBiteStrength + float64(c.Weight)))
-		if biteStrength > 255 {
-			biteStrength = 255
-		}
-
-		c.IncreaseEnergy(other.Bite(byte(biteStrength)))
+		biteStrength := uint32(math.Round(float64(c.Genome.Traits[TRAIT_BITE]) + float64(c.Weight)))
+		c.eat(object.FOOD_MEAT, other.Bite(biteStrength))
 		c.Brain.CompareFlag = CND_SUCCESS
 		c.incCounter()
 	}, true},
@@ -419,14 +437,18 @@ var commandMap = map[Command]CommandDescriptor{
 			return
 		}
 
-		shenergy := BaseReproduceEnergyCost / 2
-		c.SpendEnergy(byte(shenergy + BaseEnergyDecrement))
+		var shenergy uint32 = BaseReproduceEnergyCost / 2
+		c.SpendEnergy(shenergy + BaseEnergyDecrement)
 
-		if int(c.Energy) < shenergy {
-			shenergy = int(c.Energy)
+		if c.Energy < shenergy {
+			shenergy = c.Energy
 		}
 
-		other.IncreaseEnergy(byte(shenergy))
+		if otherCell, ok := other.(*Cell); ok {
+			otherCell.eat(object.FOOD_SHARED, shenergy)
+		} else {
+			other.IncreaseEnergy(shenergy)
+		}
 		c.Brain.CompareFlag = CND_SUCCESS
 		c.incCounter()
 	}, true},
@@ -570,7 +592,7 @@ var commandMap = map[Command]CommandDescriptor{
 			return
 		}
 
-		c.Brain.Registers[reg] = other.GetEnergy()
+		c.Brain.Registers[reg] = c.scaleEnergy(other.GetEnergy())
 		c.incCounter()
 	}, false},
 	// Get type of selected item in bag
@@ -605,13 +627,13 @@ var commandMap = map[Command]CommandDescriptor{
 	// Get self health and write to register
 	CMD_GETHEALTH: {func(c *Cell) {
 		dest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
-		c.Brain.Registers[dest] = c.GetHealth()
+		c.Brain.Registers[dest] = c.scaleHealth(c.GetHealth())
 		c.incCounter()
 	}, false},
 	// Get self energy and write to register
 	CMD_GETENERGY: {func(c *Cell) {
 		dest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
-		c.Brain.Registers[dest] = c.GetEnergy()
+		c.Brain.Registers[dest] = c.scaleEnergy(c.GetEnergy())
 		c.incCounter()
 	}, false},
 	// Get self command counter and write to register
@@ -620,6 +642,155 @@ var commandMap = map[Command]CommandDescriptor{
 		c.Brain.Registers[dest] = byte(c.Brain.CommandCounter + 1)
 		c.incCounter()
 	}, false},
+
+	// Bond with near related cell
+	CMD_BOND: {func(c *Cell) {
+		dirReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		dir := int32(c.Brain.Registers[dirReg]%8) * 45
+
+		c.SpendEnergy(BaseBondEnergyCost)
+
+		pos := c.getRelPos(object.Rotation{Degree: dir})
+		if pos.Y < 0 || pos.Y >= int32(c.World.Height) {
+			c.Brain.CompareFlag = CND_FAIL
+			c.incCounter()
+			return
+		}
+
+		other, ok := c.World.GetObjectAtPosition(pos).(*Cell)
+		if !ok || other.Died || !c.IsReleated(other) || !c.bondWith(other) {
+			c.Brain.CompareFlag = CND_FAIL
+			c.incCounter()
+			return
+		}
+
+		c.Brain.CompareFlag = CND_SUCCESS
+		c.incCounter()
+	}, true},
+	// Break bond with near cell
+	CMD_UNBOND: {func(c *Cell) {
+		dirReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		dir := int32(c.Brain.Registers[dirReg]%8) * 45
+
+		pos := c.getRelPos(object.Rotation{Degree: dir})
+		if pos.Y < 0 || pos.Y >= int32(c.World.Height) {
+			c.Brain.CompareFlag = CND_FAIL
+			c.incCounter()
+			return
+		}
+
+		o := c.World.GetObjectAtPosition(pos)
+		if o == nil || !c.breakBond(o.GetID()) {
+			c.Brain.CompareFlag = CND_FAIL
+			c.incCounter()
+			return
+		}
+
+		c.Brain.CompareFlag = CND_SUCCESS
+		c.incCounter()
+	}, true},
+	// Check if there is a bond with near cell
+	CMD_CHECKBOND: {func(c *Cell) {
+		dest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		dirReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		dir := int32(c.Brain.Registers[dirReg]%8) * 45
+
+		c.Brain.Registers[dest] = 0
+
+		o := c.World.GetObjectAtPosition(c.getRelPos(object.Rotation{Degree: dir}))
+		if o != nil && c.IsBonded(o.GetID()) {
+			c.Brain.Registers[dest] = 1
+		}
+
+		c.incCounter()
+	}, true},
+	// Get directions of all bonds relative to rotation as a bit mask
+	CMD_BONDMASK: {func(c *Cell) {
+		dest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+
+		var mask byte
+		for dir := int32(0); dir < 8; dir++ {
+			o := c.World.GetObjectAtPosition(c.getRelPos(object.Rotation{Degree: dir * 45}))
+			if o != nil && c.IsBonded(o.GetID()) {
+				mask |= 1 << dir
+			}
+		}
+
+		c.Brain.Registers[dest] = mask
+		c.incCounter()
+	}, true},
+
+	// Emit signal at own square
+	CMD_EMIT: {func(c *Cell) {
+		chReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		valReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		channel := int(c.Brain.Registers[chReg] % world.SignalChannels)
+
+		c.SpendEnergy(BaseSignalEnergyCost)
+
+		if c.World.EmitSignal(channel, c.Position, float64(c.Brain.Registers[valReg])) {
+			c.Brain.CompareFlag = CND_SUCCESS
+		} else {
+			c.Brain.CompareFlag = CND_FAIL
+		}
+		c.incCounter()
+	}, true},
+	// Get signal concentration at own square
+	CMD_SMELL: {func(c *Cell) {
+		dest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		chReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		channel := int(c.Brain.Registers[chReg] % world.SignalChannels)
+
+		c.Brain.Registers[dest] = c.World.GetSignalAtPosition(channel, c.Position)
+		c.incCounter()
+	}, false},
+	// Get direction to the near square with the highest signal concentration
+	CMD_SMELLDIR: {func(c *Cell) {
+		dest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		chReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		channel := int(c.Brain.Registers[chReg] % world.SignalChannels)
+
+		best := c.World.GetSignalAtPosition(channel, c.Position)
+		c.Brain.CompareFlag = CND_FAIL
+		for dir := int32(0); dir < 8; dir++ {
+			value := c.World.GetSignalAtPosition(channel, c.getRelPos(object.Rotation{Degree: dir * 45}))
+			if value > best {
+				best = value
+				c.Brain.Registers[dest] = byte(dir)
+				c.Brain.CompareFlag = CND_SUCCESS
+			}
+		}
+
+		c.incCounter()
+	}, false},
+
+	// Look along the direction and get distance and type of the first object
+	CMD_LOOK: {func(c *Cell) {
+		distDest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		typeDest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		dirReg := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		dir := int32(c.Brain.Registers[dirReg]%8) * 45
+
+		c.SpendEnergy(BaseLookEnergyCost)
+
+		distance, objType := c.look(object.Rotation{Degree: dir}, c.sightRange())
+		c.Brain.Registers[distDest] = byte(distance)
+		c.Brain.Registers[typeDest] = objType
+
+		if distance > 0 {
+			c.Brain.CompareFlag = CND_SUCCESS
+		} else {
+			c.Brain.CompareFlag = CND_FAIL
+		}
+		c.incCounter()
+	}, true},
+	// Get value of body trait and write to register
+	CMD_GETTRAIT: {func(c *Cell) {
+		dest := truncCmd(c.Genome.Code[c.incCounter()], RegistersCount)
+		trait := truncCmd(c.Genome.Code[c.incCounter()], TRAIT_ENUM_SIZE)
+		c.Brain.Registers[dest] = c.Genome.Traits[trait]
+		c.incCounter()
+	}, false},
 }
 
 func (c *Cell) executeCommand(cmd Command) {
@@ -627,6 +798,9 @@ func (c *Cell) executeCommand(cmd Command) {
 	if !exists {
 		return
 	}
+	if c.World.Tracer != nil {
+		c.World.Tracer.Command(c.Genome.Hash, uint64(cmd))
+	}
 	cmdDesc.handler(c)
 }
 
@@ -640,10 +814,19 @@ func (c *Cell) handleCommand(cmd Command) bool {
 		return true
 	}
 
+	if c.World.Tracer != nil {
+		c.World.Tracer.Command(c.Genome.Hash, uint64(cmd))
+	}
 	cmdDesc.handler(c)
 	return false
 }
 
+func (c *Cell) traceBranch(cmd Command, taken bool) {
+	if c.World.Tracer != nil {
+		c.World.Tracer.Branch(uint64(cmd), taken)
+	}
+}
+
 func (c *Cell) currentCommad() Command {
 	return c.Genome.Code[c.Brain.CommandCounter]
 }
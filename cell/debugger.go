@@ -0,0 +1,178 @@
+package cell
+
+const (
+	DebugHistorySize = 32
+	DebugRunLimit    = GenomeLength * 16
+)
+
+type WatchTarget byte
+
+// Watch targets list
+const (
+	WATCH_REGISTER WatchTarget = iota
+	WATCH_MEMORY
+)
+
+type StopReason byte
+
+// Debugger stop reasons list
+const (
+	STOP_STEP StopReason = iota
+	STOP_BREAKPOINT
+	STOP_WATCH
+	STOP_DIED
+	STOP_LIMIT
+)
+
+type Watch struct {
+	Target WatchTarget
+	Index  uint64
+	value  byte
+}
+
+// Executed command with the brain state right after it
+type TraceRecord struct {
+	Address     uint64
+	Command     Command
+	Registers   [RegistersCount]byte
+	CompareFlag byte
+}
+
+// Step-through debugger of a single cell, the world has to be paused
+// or locked while it is used
+type Debugger struct {
+	Cell        *Cell
+	Breakpoints map[uint64]bool
+	Watches     []Watch
+
+	history     []TraceRecord
+	historyNext int
+	historyFull bool
+}
+
+func (c *Cell) Debug(historySize int) *Debugger {
+	if historySize <= 0 {
+		historySize = DebugHistorySize
+	}
+	return &Debugger{
+		Cell:        c,
+		Breakpoints: make(map[uint64]bool),
+		history:     make([]TraceRecord, historySize),
+	}
+}
+
+// Execute the current command regardless of its synchronization
+func (c *Cell) Step() TraceRecord {
+	rec := TraceRecord{Address: c.Brain.CommandCounter, Command: c.currentCommad()}
+	c.executeCommand(rec.Command)
+	rec.Registers = c.Brain.Registers
+	rec.CompareFlag = c.Brain.CompareFlag
+	return rec
+}
+
+func (d *Debugger) Step() (TraceRecord, StopReason) {
+	if d.Cell.Died {
+		return TraceRecord{Address: d.Cell.Brain.CommandCounter}, STOP_DIED
+	}
+
+	rec := d.Cell.Step()
+	d.history[d.historyNext] = rec
+	d.historyNext = (d.historyNext + 1) % len(d.history)
+	if d.historyNext == 0 {
+		d.historyFull = true
+	}
+
+	if d.checkWatches() {
+		return rec, STOP_WATCH
+	}
+	if d.Cell.Died {
+		return rec, STOP_DIED
+	}
+	if d.Breakpoints[d.Cell.Brain.CommandCounter] {
+		return rec, STOP_BREAKPOINT
+	}
+	return rec, STOP_STEP
+}
+
+// Step until a breakpoint, a watched value change or the cell death, at most limit commands
+func (d *Debugger) Run(limit int) (steps int, reason StopReason) {
+	if limit <= 0 {
+		limit = DebugRunLimit
+	}
+	for steps < limit {
+		_, reason = d.Step()
+		if reason == STOP_DIED && steps == 0 {
+			return
+		}
+		steps++
+		if reason != STOP_STEP {
+			return
+		}
+	}
+	return steps, STOP_LIMIT
+}
+
+func (d *Debugger) SetBreakpoint(addr uint64) {
+	d.Breakpoints[addr%GenomeLength] = true
+}
+
+func (d *Debugger) ClearBreakpoint(addr uint64) {
+	delete(d.Breakpoints, addr%GenomeLength)
+}
+
+func (d *Debugger) AddWatch(target WatchTarget, index uint64) bool {
+	w := Watch{Target: target, Index: index}
+	switch target {
+	case WATCH_REGISTER:
+		if index >= RegistersCount {
+			return false
+		}
+	case WATCH_MEMORY:
+		if index >= MemorySize {
+			return false
+		}
+	default:
+		return false
+	}
+	w.value = d.watchValue(w)
+	d.Watches = append(d.Watches, w)
+	return true
+}
+
+func (d *Debugger) RemoveWatch(i int) bool {
+	if i < 0 || i >= len(d.Watches) {
+		return false
+	}
+	d.Watches = append(d.Watches[:i], d.Watches[i+1:]...)
+	return true
+}
+
+// Last executed commands, the oldest first
+func (d *Debugger) History() []TraceRecord {
+	if !d.historyFull {
+		return append([]TraceRecord(nil), d.history[:d.historyNext]...)
+	}
+	return append(append([]TraceRecord(nil), d.history[d.historyNext:]...), d.history[:d.historyNext]...)
+}
+
+func (d *Debugger) WatchValue(i int) byte {
+	return d.watchValue(d.Watches[i])
+}
+
+func (d *Debugger) watchValue(w Watch) byte {
+	if w.Target == WATCH_MEMORY {
+		return d.Cell.Brain.Memory[w.Index]
+	}
+	return d.Cell.Brain.Registers[w.Index]
+}
+
+func (d *Debugger) checkWatches() (changed bool) {
+	for i := range d.Watches {
+		value := d.watchValue(d.Watches[i])
+		if value != d.Watches[i].value {
+			d.Watches[i].value = value
+			changed = true
+		}
+	}
+	return
+}
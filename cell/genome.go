@@ -11,8 +11,9 @@ const (
 )
 
 type Genome struct {
-	Hash uint64
-	Code [GenomeLength]Command
+	Hash   uint64
+	Code   [GenomeLength]Command
+	Traits Traits
 }
 
 func (g Genome) Read(out []byte) (n int, err error) {
@@ -45,6 +46,10 @@ func (g Genome) Write(out []byte) (n int, err error) {
 	return
 }
 
+func NewGenome(code [GenomeLength]Command, traits Traits) Genome {
+	return Genome{Hash: genomeHash(code[:]), Code: code, Traits: traits}
+}
+
 func CreateBaseGenome() Genome {
 	var newGenome Genome
 	var i utils.Iterator
@@ -96,13 +101,21 @@ func CreateBaseGenome() Genome {
 	newGenome.Code[i.Inc()] = 0
 
 	newGenome.Hash = genomeHash(newGenome.Code[:])
+	newGenome.Traits = BaseTraits()
 
 	return newGenome
 }
 
 func (g Genome) Mutate() Genome {
-	for i := 0; i < GenomeMutationRate; i++ {
+	return g.MutateN(GenomeMutationRate)
+}
+
+func (g Genome) MutateN(rate int) Genome {
+	for i := 0; i < rate; i++ {
 		g.Code[rand.Intn(GenomeLength)] = Command(rand.Intn(256))
+		if rand.Float64() < TraitMutationChance {
+			g.Traits = g.Traits.Mutate()
+		}
 	}
 
 	g.Hash = genomeHash(g.Code[:])
@@ -0,0 +1,130 @@
+package cell
+
+import "gopher-dish/object"
+
+// Bondable interface implementation
+
+func (c *Cell) GetBonds() []uint64 {
+	bonds := make([]uint64, 0, BondsCount)
+	for _, id := range c.Bonds {
+		if id != 0 {
+			bonds = append(bonds, id)
+		}
+	}
+	return bonds
+}
+
+func (c *Cell) AddBond(id uint64) bool {
+	if id == 0 || id == c.Name || c.IsBonded(id) {
+		return false
+	}
+
+	for i := range c.Bonds {
+		if c.Bonds[i] == 0 {
+			c.Bonds[i] = id
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Cell) RemoveBond(id uint64) bool {
+	for i := range c.Bonds {
+		if c.Bonds[i] == id {
+			c.Bonds[i] = 0
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Cell) IsBonded(id uint64) bool {
+	for _, bond := range c.Bonds {
+		if bond != 0 && bond == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cell) bondWith(other object.Bondable) bool {
+	if !c.AddBond(other.GetID()) {
+		return false
+	}
+	if !other.AddBond(c.Name) {
+		c.RemoveBond(other.GetID())
+		return false
+	}
+	return true
+}
+
+func (c *Cell) breakBond(id uint64) bool {
+	if !c.RemoveBond(id) {
+		return false
+	}
+	if other, ok := c.World.GetObject(id).(object.Bondable); ok {
+		other.RemoveBond(c.Name)
+	}
+	return true
+}
+
+func (c *Cell) pruneBonds() {
+	for _, id := range c.Bonds {
+		if id == 0 {
+			continue
+		}
+		other, ok := c.World.GetObject(id).(*Cell)
+		if !ok || other.Died || !c.isAdjacent(other.Position) {
+			c.breakBond(id)
+		}
+	}
+}
+
+func (c *Cell) shareBondsEnergy() {
+	for _, id := range c.Bonds {
+		if id == 0 {
+			continue
+		}
+		other, ok := c.World.GetObject(id).(*Cell)
+		if !ok || other.Died || other.Energy >= c.Energy {
+			continue
+		}
+		share := (c.Energy - other.Energy) / BondShareDivider
+		// the part over the receiver capacity stays with the giver
+		c.Energy -= other.eat(object.FOOD_SHARED, share)
+	}
+}
+
+// Collect cells of the colony this cell belongs to
+func (c *Cell) colony() []*Cell {
+	colony := []*Cell{c}
+	visited := map[uint64]bool{c.Name: true}
+
+	for i := 0; i < len(colony) && len(colony) < MaxColonySize; i++ {
+		for _, id := range colony[i].Bonds {
+			if id == 0 || visited[id] {
+				continue
+			}
+			visited[id] = true
+			if other, ok := c.World.GetObject(id).(*Cell); ok && !other.Died {
+				colony = append(colony, other)
+			}
+		}
+	}
+
+	return colony
+}
+
+func (c *Cell) isAdjacent(pos object.Position) bool {
+	dx := pos.X - c.Position.X
+	if dx < 0 {
+		dx = -dx
+	}
+	if dx == int32(c.World.Width)-1 {
+		dx = 1
+	}
+	dy := pos.Y - c.Position.Y
+	return dx <= 1 && dy >= -1 && dy <= 1
+}
@@ -0,0 +1,9 @@
+package cell
+
+import "gopher-dish/object"
+
+// Eater interface implementation
+
+func (c *Cell) GetDiet() object.Diet {
+	return c.Diet
+}
@@ -0,0 +1,30 @@
+package cell
+
+import "gopher-dish/object"
+
+// Editable interface implementation
+
+func (c *Cell) Snapshot() func() {
+	saved := *c
+	return func() {
+		*c = saved
+	}
+}
+
+func (c *Cell) SetPosition(pos object.Position) {
+	c.Position = pos
+}
+
+func (c *Cell) SetEnergy(energy uint32) {
+	if capacity := c.energyCapacity(); energy > capacity {
+		energy = capacity
+	}
+	c.Energy = energy
+}
+
+func (c *Cell) SetHealth(health uint32) {
+	if health > c.World.Config.MaxHealth {
+		health = c.World.Config.MaxHealth
+	}
+	c.Health = health
+}
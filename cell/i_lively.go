@@ -11,6 +11,10 @@ func (c *Cell) GetAge() uint32 {
 	return c.Age
 }
 
+func (c *Cell) GetGeneration() uint64 {
+	return c.Generation
+}
+
 func (c *Cell) GetGenomeHash() uint64 {
 	return c.Genome.Hash
 }
@@ -19,11 +23,11 @@ func (c *Cell) GetParentsChain() object.ParentsChain {
 	return c.ParentsChain
 }
 
-func (c *Cell) GetHealth() byte {
+func (c *Cell) GetHealth() uint32 {
 	return c.Health
 }
 
-func (c *Cell) LoseHealth(health byte) bool {
+func (c *Cell) LoseHealth(health uint32, cause object.DeathCause) bool {
 	if c.Died {
 		return false
 	}
@@ -31,18 +35,22 @@ func (c *Cell) LoseHealth(health byte) bool {
 	if health < c.Health {
 		c.Health -= health
 	} else {
-		c.Die()
+		c.Die(cause)
 	}
 
 	return true
 }
 
-func (c *Cell) HealHealth(health byte) bool {
+func (c *Cell) HealHealth(health uint32) bool {
 	if c.Died {
 		return false
 	}
 
-	c.Health += health
+	if uint64(c.Health)+uint64(health) > uint64(c.World.Config.MaxHealth) {
+		c.Health = c.World.Config.MaxHealth
+	} else {
+		c.Health += health
+	}
 	return true
 }
 
@@ -80,20 +88,18 @@ func (c *Cell) Reproduce(rot object.Rotation) bool {
 	return true
 }
 
-func (c *Cell) Bite(strength byte) byte {
+func (c *Cell) Bite(strength uint32) uint32 {
 	if c.Died {
 		c.World.RemoveObject(c.Name)
 		return c.Energy
 	}
 
-	biteStrength := int(math.Round(float64(strength) + float64(c.Age)*AgeInfluenceMultiplier - float64(c.Weight)))
-	if biteStrength > 255 {
-		biteStrength = 255
-	} else if biteStrength <= 0 {
+	biteStrength := int(math.Round(float64(strength) + float64(c.Age)*AgeInfluenceMultiplier - float64(c.Weight) - float64(c.Genome.Traits[TRAIT_ARMOUR])))
+	if biteStrength <= 0 {
 		return 0
 	}
 
-	c.SpendEnergy(byte(biteStrength))
+	c.spendEnergy(uint32(biteStrength), object.DEATH_BITE)
 	c.Killed = true
 
 	var energy int
@@ -104,16 +110,17 @@ func (c *Cell) Bite(strength byte) byte {
 		energy = biteStrength - int(math.Round(float64(c.Age)*AgeInfluenceMultiplier)) + int(c.Weight)
 	}
 
-	if energy > 255 {
-		energy = 255
-	} else if energy <= 0 {
+	if energy <= 0 {
 		return 0
 	}
 
-	return byte(energy)
+	return uint32(energy)
 }
 
-func (c *Cell) Die() bool {
+func (c *Cell) Die(cause object.DeathCause) bool {
+	if !c.Died {
+		c.World.Metrics.Death(cause)
+	}
 	c.Energy += BaseEnergyDecrement
 	c.Health = 0
 	c.Died = true
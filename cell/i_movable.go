@@ -32,12 +32,40 @@ func (c *Cell) MoveRight() bool {
 }
 
 func (c *Cell) MoveToPosition(pos object.Position) bool {
-	c.SpendEnergy(c.Weight)
+	c.SpendEnergy(uint32(c.Weight))
 	pos.X = (pos.X + int32(c.World.Width)) % int32(c.World.Width)
-	if !c.World.MoveObject(c, pos) {
+
+	colony := c.colony()
+	if len(colony) == 1 {
+		if !c.World.MoveObject(c, pos) {
+			return false
+		}
+		c.Position = pos
+		return true
+	}
+
+	// Bonded cells move as a unit
+	dx, dy := pos.X-c.Position.X, pos.Y-c.Position.Y
+	objects := make([]object.Movable, len(colony))
+	positions := make([]object.Position, len(colony))
+	for i, member := range colony {
+		objects[i] = member
+		positions[i] = object.Position{
+			X: (member.Position.X + dx + int32(c.World.Width)) % int32(c.World.Width),
+			Y: member.Position.Y + dy,
+		}
+	}
+
+	if !c.World.MoveObjects(objects, positions) {
 		return false
 	}
-	c.Position = pos
+
+	for i, member := range colony {
+		if member != c {
+			member.SpendEnergy(uint32(member.Weight))
+		}
+		member.Position = positions[i]
+	}
 	return true
 }
 
@@ -47,6 +75,6 @@ func (c *Cell) MoveInDirection(rot object.Rotation) bool {
 
 func (c *Cell) Rotate(rot object.Rotation) bool {
 	c.Rotation.Degree = int32(math.Round(float64(c.Rotation.Rotate(rot.Degree).Degree)/45.0)) * 45
-	c.SpendEnergy(c.Weight / 4)
+	c.SpendEnergy(uint32(c.Weight / 4))
 	return true
 }
@@ -17,10 +17,12 @@ func (c *Cell) Prepare() {
 	if c.Died {
 		return
 	} else if c.Health <= 0 {
-		c.Die()
+		c.Die(object.DEATH_WOUNDS)
 		return
 	}
 
+	c.pruneBonds()
+	c.shareBondsEnergy()
 	c.executeCommand(c.currentCommad())
 }
 
@@ -28,7 +30,7 @@ func (c *Cell) Handle(yearChanged, epochChanged bool) {
 	if c.Died {
 		return
 	} else if c.Health <= 0 {
-		c.Die()
+		c.Die(object.DEATH_WOUNDS)
 		return
 	}
 
@@ -61,6 +63,7 @@ func (c *Cell) Save(writer io.Writer) (err error) {
 		Picked:       c.Picked,
 		Genome:       c.Genome,
 		Brain:        c.Brain,
+		Bonds:        c.Bonds,
 		Position:     c.Position,
 		Rotation:     c.Rotation,
 	}
@@ -77,36 +80,46 @@ func (c *Cell) Save(writer io.Writer) (err error) {
 	return binary.Write(writer, binary.LittleEndian, cdesc)
 }
 
-func (c *Cell) GetEnergy() byte {
+func (c *Cell) GetEnergy() uint32 {
 	return c.Energy
 }
 
-func (c *Cell) SpendEnergy(energy byte) bool {
-	energyDec := uint32(math.Round(float64(energy) + float64(c.Age)*AgeInfluenceMultiplier))
-	if energyDec < uint32(c.Energy) {
+func (c *Cell) SpendEnergy(energy uint32) bool {
+	return c.spendEnergy(energy, object.DEATH_STARVATION)
+}
+
+// Energy shortage is taken from the health, the cause is counted if the cell dies
+func (c *Cell) spendEnergy(energy uint32, cause object.DeathCause) bool {
+	energyDecF := math.Round(float64(energy) + float64(c.Age)*AgeInfluenceMultiplier + c.Genome.Traits.Upkeep())
+	if energyDecF < 0 {
+		energyDecF = 0
+	}
+
+	energyDec := uint32(energyDecF)
+	if energyDec < c.Energy {
 		// Decrement energy
-		c.Energy -= byte(energyDec)
+		c.Energy -= energyDec
 	} else {
 		// If there is no energy then decrement health
-		energyDec -= uint32(c.Energy)
+		energyDec -= c.Energy
 		c.Energy = 0
 		healthDec := uint32(math.Round(float64(energyDec) + BaseHealthDecrement + float64(c.Age)*AgeInfluenceMultiplier))
-		if healthDec > 255 {
-			healthDec = 255
-		}
-		c.LoseHealth(byte(healthDec))
+		c.LoseHealth(healthDec, cause)
 	}
 
 	return true
 }
 
-func (c *Cell) IncreaseEnergy(energy byte) bool {
+func (c *Cell) IncreaseEnergy(energy uint32) bool {
 	if c.Died {
 		return false
 	}
 
-	if int(c.Energy)+int(energy) > 255 {
-		c.Energy = 255
+	capacity := c.energyCapacity()
+	if uint64(c.Energy)+uint64(energy) > uint64(capacity) {
+		if c.Energy < capacity {
+			c.Energy = capacity
+		}
 	} else {
 		c.Energy += energy
 	}
@@ -0,0 +1,70 @@
+package cell
+
+import "math/rand"
+
+const (
+	TraitMutationChance = 0.125
+	TraitMutationStep   = 2
+	BasePhotosynthesis  = 16
+	BaseSight           = 8
+	BaseMaxEnergy       = 255
+)
+
+// Traits list
+const (
+	TRAIT_WEIGHT = iota
+	TRAIT_ARMOUR
+	TRAIT_BITE
+	TRAIT_PHOTOSYNTHESIS
+	TRAIT_MAXENERGY
+	TRAIT_SIGHT
+
+	TRAIT_ENUM_SIZE
+)
+
+// Body traits inherited and mutated along with the genome code
+type Traits [TRAIT_ENUM_SIZE]byte
+
+type traitDescriptor struct {
+	base, min, max byte
+	// Energy spent per trait point above the base value, saved below it
+	upkeep float64
+}
+
+var traitMap = [TRAIT_ENUM_SIZE]traitDescriptor{
+	TRAIT_WEIGHT:         {BaseWeight, 1, 64, 0},
+	TRAIT_ARMOUR:         {0, 0, 64, 0.05},
+	TRAIT_BITE:           {BaseBiteStrength, 0, 255, 0.01},
+	TRAIT_PHOTOSYNTHESIS: {BasePhotosynthesis, 0, 64, 0.04},
+	TRAIT_MAXENERGY:      {BaseMaxEnergy, 32, 255, 0.004},
+	TRAIT_SIGHT:          {BaseSight, 0, 64, 0.02},
+}
+
+func BaseTraits() (t Traits) {
+	for i, desc := range traitMap {
+		t[i] = desc.base
+	}
+	return
+}
+
+func (t Traits) Mutate() Traits {
+	i := rand.Intn(TRAIT_ENUM_SIZE)
+	value := int(t[i]) + rand.Intn(TraitMutationStep*2+1) - TraitMutationStep
+
+	if value < int(traitMap[i].min) {
+		value = int(traitMap[i].min)
+	} else if value > int(traitMap[i].max) {
+		value = int(traitMap[i].max)
+	}
+
+	t[i] = byte(value)
+	return t
+}
+
+// Energy spent on top of every action to maintain the body
+func (t Traits) Upkeep() (upkeep float64) {
+	for i, desc := range traitMap {
+		upkeep += (float64(t[i]) - float64(desc.base)) * desc.upkeep
+	}
+	return
+}
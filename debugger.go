@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gopher-dish/cell"
+	"gopher-dish/utils/genasm"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const debuggerHelp = `Commands:
+    s, step [n]           execute n commands
+    c, continue [limit]   run to a breakpoint or a watched value change
+    b, break <addr>       set breakpoint on the genome address
+    d, delete <addr>      remove breakpoint
+    w, watch <rN|mN>      watch register or memory byte
+    u, unwatch <n>        remove watch by its number
+    r, regs               show registers, flags and counters
+    m, mem                show memory
+    h, history            show last executed commands
+    l, list [addr] [n]    disassemble n commands from the address
+    i, info               show cell state
+    q, quit               exit
+`
+
+var stopReasonName = map[cell.StopReason]string{
+	cell.STOP_STEP:       "step",
+	cell.STOP_BREAKPOINT: "breakpoint",
+	cell.STOP_WATCH:      "watch",
+	cell.STOP_DIED:       "died",
+	cell.STOP_LIMIT:      "limit",
+}
+
+func runDebugger(c *cell.Cell, in io.Reader, out io.Writer) {
+	d := c.Debug(cell.DebugHistorySize)
+	scanner := bufio.NewScanner(in)
+
+	fmt.Fprintf(out, "Debugging cell %d, type 'help' for the commands list\n", c.Name)
+	printDebuggerCommand(out, d, c.Brain.CommandCounter)
+
+	for {
+		fmt.Fprint(out, "(debug) ")
+		if !scanner.Scan() {
+			return
+		}
+
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "s", "step":
+			n := parseDebuggerArg(out, args, 1, 1)
+			for i := uint64(0); i < n; i++ {
+				rec, reason := d.Step()
+				printDebuggerRecord(out, c, rec)
+				if reason != cell.STOP_STEP {
+					fmt.Fprintf(out, "Stopped: %s\n", stopReasonName[reason])
+					break
+				}
+			}
+			printDebuggerCommand(out, d, c.Brain.CommandCounter)
+
+		case "c", "continue":
+			limit := parseDebuggerArg(out, args, 1, cell.DebugRunLimit)
+			steps, reason := d.Run(int(limit))
+			fmt.Fprintf(out, "Stopped after %d commands: %s\n", steps, stopReasonName[reason])
+			printDebuggerCommand(out, d, c.Brain.CommandCounter)
+
+		case "b", "break":
+			if len(args) < 2 {
+				for addr := range d.Breakpoints {
+					fmt.Fprintf(out, "    %3d\n", addr)
+				}
+				continue
+			}
+			d.SetBreakpoint(parseDebuggerArg(out, args, 1, 0))
+
+		case "d", "delete":
+			d.ClearBreakpoint(parseDebuggerArg(out, args, 1, 0))
+
+		case "w", "watch":
+			if len(args) < 2 {
+				for i, w := range d.Watches {
+					fmt.Fprintf(out, "    %d: %s = %d\n", i, watchName(w), d.WatchValue(i))
+				}
+				continue
+			}
+			target := cell.WATCH_REGISTER
+			if strings.HasPrefix(args[1], "m") {
+				target = cell.WATCH_MEMORY
+			}
+			index, err := strconv.ParseUint(strings.TrimLeft(args[1], "rm"), 10, 64)
+			if err != nil || !d.AddWatch(target, index) {
+				fmt.Fprintln(out, "Invalid watch target:", args[1])
+			}
+
+		case "u", "unwatch":
+			if !d.RemoveWatch(int(parseDebuggerArg(out, args, 1, 0))) {
+				fmt.Fprintln(out, "No such watch")
+			}
+
+		case "r", "regs":
+			b := &c.Brain
+			for i, r := range b.Registers {
+				fmt.Fprintf(out, "    r%d = %3d\n", i, r)
+			}
+			fmt.Fprintf(out, "    flag = %08b  counter = %d  stack = %d\n", b.CompareFlag, b.CommandCounter, b.StackCounter)
+
+		case "m", "mem":
+			for i := 0; i < cell.MemorySize; i += 16 {
+				fmt.Fprintf(out, "    %2d: % 4d\n", i, c.Brain.Memory[i:i+16])
+			}
+
+		case "h", "history":
+			for _, rec := range d.History() {
+				printDebuggerRecord(out, c, rec)
+			}
+
+		case "l", "list":
+			addr := parseDebuggerArg(out, args, 1, c.Brain.CommandCounter)
+			n := parseDebuggerArg(out, args, 2, 8)
+			for i := uint64(0); i < n; i++ {
+				addr = printDebuggerCommand(out, d, addr)
+			}
+
+		case "i", "info":
+			fmt.Fprintf(out, "    id = %d  generation = %d  age = %d\n", c.Name, c.Generation, c.Age)
+			fmt.Fprintf(out, "    health = %d  energy = %d  died = %t\n", c.Health, c.Energy, c.Died)
+			fmt.Fprintf(out, "    position = [%d, %d]  rotation = %d\n", c.Position.X, c.Position.Y, c.Rotation.Degree)
+
+		case "help":
+			fmt.Fprint(out, debuggerHelp)
+
+		case "q", "quit":
+			return
+
+		default:
+			fmt.Fprintf(out, "Unknown command '%s', type 'help' for the commands list\n", args[0])
+		}
+	}
+}
+
+func parseDebuggerArg(out io.Writer, args []string, i int, def uint64) uint64 {
+	if len(args) <= i {
+		return def
+	}
+	num, err := strconv.ParseUint(args[i], 10, 64)
+	if err != nil {
+		fmt.Fprintln(out, "Invalid number:", args[i])
+		return def
+	}
+	return num
+}
+
+func printDebuggerCommand(out io.Writer, d *cell.Debugger, addr uint64) uint64 {
+	code, size := genasm.DisassembleCommand(d.Cell.Genome, addr)
+	marker := ' '
+	if d.Breakpoints[addr%cell.GenomeLength] {
+		marker = '*'
+	}
+	if addr%cell.GenomeLength == d.Cell.Brain.CommandCounter {
+		marker = '>'
+	}
+	fmt.Fprintf(out, "%c %3d: %s\n", marker, addr%cell.GenomeLength, code)
+	return (addr + size) % cell.GenomeLength
+}
+
+func printDebuggerRecord(out io.Writer, c *cell.Cell, rec cell.TraceRecord) {
+	code, _ := genasm.DisassembleCommand(c.Genome, rec.Address)
+	fmt.Fprintf(out, "  %3d: %-24s regs = % 4d  flag = %08b\n", rec.Address, code, rec.Registers, rec.CompareFlag)
+}
+
+func watchName(w cell.Watch) string {
+	if w.Target == cell.WATCH_MEMORY {
+		return fmt.Sprintf("m%d", w.Index)
+	}
+	return fmt.Sprintf("r%d", w.Index)
+}
@@ -0,0 +1,75 @@
+package gui
+
+import (
+	"math"
+
+	"gopher-dish/object"
+
+	"github.com/faiface/pixel"
+)
+
+const (
+	// View shift per frame of the keyboard panning
+	KeyPanSpeed = 12
+)
+
+// Bounds of the target the drawer is drawn to
+func (wd *WorldDrawer) SetView(view pixel.Rect) {
+	wd.view = view
+}
+
+func (wd *WorldDrawer) Move(p pixel.Vec) {
+	wd.matrix = wd.matrix.Moved(p)
+}
+
+// Largest zoom showing the whole world, centered in the view
+func (wd *WorldDrawer) Fit() {
+	zoom := math.Floor(math.Min(wd.view.W()/float64(wd.world.Width), wd.view.H()/float64(wd.world.Height)))
+	wd.setZoom(zoom, wd.view.Center())
+	wd.LookAt(pixel.V(float64(wd.world.Width)/2, float64(wd.world.Height)/2))
+}
+
+// Move the world point, in squares from the top left corner, to the view center
+func (wd *WorldDrawer) LookAt(p pixel.Vec) {
+	current := wd.WorldAt(wd.view.Center())
+	wd.Move(pixel.V(current.X-p.X, p.Y-current.Y).Scaled(wd.zoom))
+}
+
+// World point, in squares from the top left corner, under the point of the view
+func (wd *WorldDrawer) WorldAt(v pixel.Vec) pixel.Vec {
+	p := wd.matrix.Unproject(v).Add(wd.canvas.Bounds().Center())
+	return pixel.V(p.X/wd.zoom, (wd.bounds.Max.Y-p.Y)/wd.zoom)
+}
+
+// World position under the point of the target the drawer is drawn to
+func (wd *WorldDrawer) PositionAt(v pixel.Vec) (object.Position, bool) {
+	p := wd.WorldAt(v)
+	x, y := int32(math.Floor(p.X)), int32(math.Floor(p.Y))
+	if x < 0 || x >= int32(wd.world.Width) || y < 0 || y >= int32(wd.world.Height) {
+		return object.Position{}, false
+	}
+	return object.Position{X: x, Y: y}, true
+}
+
+// Visible part of the world in squares from the top left corner
+func (wd *WorldDrawer) Viewport() pixel.Rect {
+	return pixel.Rect{Min: wd.WorldAt(wd.view.Min), Max: wd.WorldAt(wd.view.Max)}.Norm()
+}
+
+// Called under the world lock, stops following when the object is gone
+func (wd *WorldDrawer) follow() {
+	if !wd.Follow {
+		return
+	}
+	o := wd.world.GetObject(wd.Selected)
+	if o == nil {
+		wd.Follow = false
+		return
+	}
+	pos := o.GetPosition()
+	wd.LookAt(pixel.V(float64(pos.X)+0.5, float64(pos.Y)+0.5))
+}
+
+func (wd *WorldDrawer) canvasCenter(zoom float64) pixel.Vec {
+	return pixel.V(float64(wd.world.Width)*zoom/2, float64(wd.world.Height)*zoom/2)
+}
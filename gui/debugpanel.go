@@ -0,0 +1,110 @@
+package gui
+
+import (
+	"fmt"
+
+	"gopher-dish/cell"
+	"gopher-dish/gui/fonts"
+	"gopher-dish/object"
+	"gopher-dish/utils/genasm"
+	"gopher-dish/world"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/text"
+)
+
+const (
+	DebugPanelListing = 8
+	DebugPanelHistory = 6
+)
+
+// Side panel with the VM state of the selected cell
+type DebugPanel struct {
+	world    *world.World
+	debugger *cell.Debugger
+	text     *text.Text
+}
+
+func NewDebugPanel(w *world.World) *DebugPanel {
+	return &DebugPanel{
+		world: w,
+		text:  text.New(pixel.V(0, 0), fonts.RedhatMonoMedium12),
+	}
+}
+
+// Attach the debugger to the selected object, the caller holds the world lock
+func (dp *DebugPanel) Select(obj object.Movable) {
+	c, ok := obj.(*cell.Cell)
+	if !ok {
+		dp.debugger = nil
+		return
+	}
+	dp.debugger = c.Debug(DebugPanelHistory)
+}
+
+// Execute one command of the selected cell, works only while the world is paused
+func (dp *DebugPanel) Step() bool {
+	if dp.debugger == nil || !dp.world.Paused {
+		return false
+	}
+
+	dp.world.PlacesDrawMux.Lock()
+	defer dp.world.PlacesDrawMux.Unlock()
+	dp.world.EnterStepping()
+	dp.debugger.Step()
+	return true
+}
+
+func (dp *DebugPanel) Draw(t pixel.Target, pos pixel.Vec) {
+	dp.text.Clear()
+	dp.text.Color = pixel.RGB(0.2, 0.2, 0.2)
+	dp.text.Orig = pos
+	dp.text.Dot = pos
+
+	if dp.debugger == nil {
+		return
+	}
+
+	dp.world.PlacesDrawMux.Lock()
+	dp.print()
+	dp.world.PlacesDrawMux.Unlock()
+
+	dp.text.Draw(t, pixel.IM)
+}
+
+func (dp *DebugPanel) print() {
+	c := dp.debugger.Cell
+	if dp.world.GetObject(c.Name) != c {
+		fmt.Fprintf(dp.text, "Cell %d is removed\n", c.Name)
+		return
+	}
+
+	fmt.Fprintf(dp.text, "Cell %d  gen %d  age %d\n", c.Name, c.Generation, c.Age)
+	fmt.Fprintf(dp.text, "Health %d  energy %d  died %t\n", c.Health, c.Energy, c.Died)
+	fmt.Fprintf(dp.text, "Position [%d, %d]  rotation %d\n\n", c.Position.X, c.Position.Y, c.Rotation.Degree)
+
+	b := &c.Brain
+	fmt.Fprintf(dp.text, "Registers % 4d\n", b.Registers)
+	fmt.Fprintf(dp.text, "Flag %08b  stack %d\n\n", b.CompareFlag, b.StackCounter)
+
+	addr := b.CommandCounter
+	for i := 0; i < DebugPanelListing; i++ {
+		code, size := genasm.DisassembleCommand(c.Genome, addr)
+		marker := ' '
+		if i == 0 {
+			marker = '>'
+		}
+		fmt.Fprintf(dp.text, "%c %3d: %s\n", marker, addr, code)
+		addr = (addr + size) % cell.GenomeLength
+	}
+
+	history := dp.debugger.History()
+	if len(history) == 0 {
+		return
+	}
+	fmt.Fprint(dp.text, "\nHistory:\n")
+	for i := len(history) - 1; i >= 0; i-- {
+		code, _ := genasm.DisassembleCommand(c.Genome, history[i].Address)
+		fmt.Fprintf(dp.text, "  %3d: %s\n", history[i].Address, code)
+	}
+}
@@ -0,0 +1,113 @@
+package gui
+
+import (
+	"gopher-dish/object"
+	"gopher-dish/world"
+)
+
+const (
+	EditBrushRadius = 3
+	EditEnergyStep  = 10
+	EditHealthStep  = 10
+	EditPaintStep   = 4
+)
+
+// Applies the edit tools to the paused world, one mouse stroke is one undo step
+type Editor struct {
+	world  *world.World
+	drawer *WorldDrawer
+
+	active  bool
+	dragged uint64
+}
+
+func NewEditor(w *world.World, wd *WorldDrawer) *Editor {
+	return &Editor{world: w, drawer: wd}
+}
+
+// Start the stroke, decrease reverses the energy, health and paint tools
+func (e *Editor) Press(tool Tool, pos object.Position, decrease bool) {
+	if !e.world.Paused {
+		return
+	}
+
+	e.active = true
+	e.world.BeginEdit()
+
+	switch tool {
+	case TOOL_ERASE:
+		e.drawer.Area = [2]object.Position{pos, pos}
+		e.drawer.AreaVisible = true
+	case TOOL_DRAG:
+		e.dragged = 0
+		if obj := e.objectAt(pos); obj != nil {
+			e.dragged = obj.GetID()
+		}
+	case TOOL_ENERGY:
+		if obj := e.objectAt(pos); obj != nil {
+			e.world.SetObjectEnergy(obj.GetID(), adjust(obj.GetEnergy(), EditEnergyStep, decrease))
+		}
+	case TOOL_HEALTH:
+		if lively, ok := e.objectAt(pos).(object.Lively); ok {
+			e.world.SetObjectHealth(lively.GetID(), adjust(lively.GetHealth(), EditHealthStep, decrease))
+		}
+	default:
+		e.Hold(tool, pos, decrease)
+	}
+}
+
+func (e *Editor) Hold(tool Tool, pos object.Position, decrease bool) {
+	if !e.active {
+		return
+	}
+
+	switch tool {
+	case TOOL_KILL:
+		e.world.KillInRadius(pos, EditBrushRadius)
+	case TOOL_ERASE:
+		e.drawer.Area[1] = pos
+	case TOOL_DRAG:
+		if e.dragged != 0 {
+			e.world.DragObject(e.dragged, pos)
+		}
+	case TOOL_SUNLIGHT, TOOL_MINERALS:
+		value := float64(EditPaintStep)
+		if decrease {
+			value = -value
+		}
+		field := world.FIELD_SUNLIGHT
+		if tool == TOOL_MINERALS {
+			field = world.FIELD_MINERALS
+		}
+		e.world.PaintField(field, pos, EditBrushRadius, value)
+	}
+}
+
+func (e *Editor) Release(tool Tool) {
+	if !e.active {
+		return
+	}
+
+	if tool == TOOL_ERASE {
+		e.world.EraseRect(e.drawer.Area[0], e.drawer.Area[1])
+	}
+	e.drawer.AreaVisible = false
+	e.active = false
+	e.world.EndEdit()
+}
+
+func (e *Editor) objectAt(pos object.Position) object.Movable {
+	e.world.PlacesDrawMux.Lock()
+	defer e.world.PlacesDrawMux.Unlock()
+	return e.world.GetObjectAtPosition(pos)
+}
+
+func adjust(value, step uint32, decrease bool) uint32 {
+	if !decrease {
+		return value + step
+	}
+	if value < step {
+		return 0
+	}
+	return value - step
+}
@@ -0,0 +1,160 @@
+package gui
+
+import (
+	"fmt"
+	"math"
+
+	"gopher-dish/gui/fonts"
+	"gopher-dish/world"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/text"
+)
+
+const (
+	GraphPanelPlots = 5
+	// Space between the plots taken by their titles
+	graphTitleHeight = 18
+)
+
+var (
+	colorGraphFrame      = pixel.RGB(0.83, 0.83, 0.83)
+	colorGraphPopulation = pixel.RGB(0.2, 0.2, 0.2)
+	colorGraphEnergy     = pixel.RGB(1, 0.6, 0)
+	colorGraphGeneration = pixel.RGB(0.23, 0.32, 0.55)
+	colorGraphGenomes    = pixel.RGB(0.7, 0.2, 0.6)
+)
+
+type graphSeries struct {
+	color  pixel.RGBA
+	values []float64
+}
+
+// Plots of the world history in the side panel
+type GraphPanel struct {
+	Visible bool
+
+	world  *world.World
+	drawer *imdraw.IMDraw
+	text   *text.Text
+}
+
+func NewGraphPanel(w *world.World) *GraphPanel {
+	return &GraphPanel{
+		world:  w,
+		drawer: imdraw.New(nil),
+		text:   text.New(pixel.V(0, 0), fonts.RedhatMonoMedium12),
+	}
+}
+
+func (gp *GraphPanel) Draw(t pixel.Target, bounds pixel.Rect) {
+	if !gp.Visible {
+		return
+	}
+
+	gp.world.PlacesDrawMux.Lock()
+	samples := gp.world.History.Samples()
+	gp.world.PlacesDrawMux.Unlock()
+
+	gp.drawer.Clear()
+	gp.text.Clear()
+	gp.text.Color = pixel.RGB(0.2, 0.2, 0.2)
+	if len(samples) < 2 {
+		return
+	}
+
+	population := make([]float64, len(samples))
+	living := make([]float64, len(samples))
+	dead := make([]float64, len(samples))
+	energy := make([]float64, len(samples))
+	generation := make([]float64, len(samples))
+	genomes := make([]float64, len(samples))
+	for i, s := range samples {
+		population[i] = float64(s.Population)
+		living[i] = float64(s.Living)
+		dead[i] = float64(s.Dead)
+		energy[i] = float64(s.MeanEnergy)
+		generation[i] = float64(s.MeanGeneration)
+		genomes[i] = float64(s.Genomes)
+	}
+	last := samples[len(samples)-1]
+
+	plotHeight := bounds.H()/GraphPanelPlots - graphTitleHeight
+	plot := func(n int) pixel.Rect {
+		top := bounds.Max.Y - float64(n)*(plotHeight+graphTitleHeight) - graphTitleHeight
+		return pixel.R(bounds.Min.X, top-plotHeight, bounds.Max.X, top)
+	}
+
+	gp.plot(plot(0), fmt.Sprintf("Population %d | living %d | dead %d", last.Population, last.Living, last.Dead),
+		graphSeries{colorGraphPopulation, population}, graphSeries{colorObjectLively, living}, graphSeries{colorObjectDied, dead})
+	gp.plot(plot(1), fmt.Sprintf("Mean energy %.1f", last.MeanEnergy), graphSeries{colorGraphEnergy, energy})
+	gp.plot(plot(2), fmt.Sprintf("Mean generation %.1f", last.MeanGeneration), graphSeries{colorGraphGeneration, generation})
+	gp.plot(plot(3), fmt.Sprintf("Genomes %d", last.Genomes), graphSeries{colorGraphGenomes, genomes})
+	gp.plotSunlight(plot(4), samples)
+
+	gp.drawer.Draw(t)
+	gp.text.Draw(t, pixel.IM)
+}
+
+// Series share the scale from zero to their maximum
+func (gp *GraphPanel) plot(r pixel.Rect, title string, series ...graphSeries) {
+	gp.frame(r, title)
+
+	max := 1.0
+	for _, s := range series {
+		for _, v := range s.values {
+			max = math.Max(max, v)
+		}
+	}
+
+	for _, s := range series {
+		gp.drawer.Color = s.color
+		step := graphStep(len(s.values), r.W())
+		for i := 0; i < len(s.values); i += step {
+			x := r.Min.X + r.W()*float64(i)/float64(len(s.values)-1)
+			gp.drawer.Push(pixel.V(x, r.Min.Y+r.H()*s.values[i]/max))
+		}
+		gp.drawer.Line(1)
+	}
+}
+
+// Band between the sunlight begin and end, the world top is up
+func (gp *GraphPanel) plotSunlight(r pixel.Rect, samples []world.HistorySample) {
+	last := samples[len(samples)-1]
+	gp.frame(r, fmt.Sprintf("Sunlight band %.2f - %.2f", last.SunlightBegin, last.SunlightEnd))
+
+	gp.drawer.Color = colorSunlight
+	step := graphStep(len(samples), r.W())
+	width := r.W() * float64(step) / float64(len(samples)-1)
+	for i := 0; i < len(samples); i += step {
+		x := r.Min.X + r.W()*float64(i)/float64(len(samples)-1)
+		// The gradient may reach beyond the world edges
+		top := r.Max.Y - r.H()*clamp01(float64(samples[i].SunlightBegin))
+		bottom := r.Max.Y - r.H()*clamp01(float64(samples[i].SunlightEnd))
+		gp.drawer.Push(pixel.V(x, bottom), pixel.V(math.Min(x+width, r.Max.X), top))
+		gp.drawer.Rectangle(0)
+	}
+}
+
+func (gp *GraphPanel) frame(r pixel.Rect, title string) {
+	gp.drawer.Color = colorGraphFrame
+	gp.drawer.Push(r.Min, r.Max)
+	gp.drawer.Rectangle(1)
+
+	gp.text.Dot = pixel.V(r.Min.X, r.Max.Y+4)
+	fmt.Fprint(gp.text, title)
+}
+
+// Samples per drawn point, at most one point per pixel
+func graphStep(samples int, width float64) int {
+	step := int(float64(samples) / width)
+	if step < 1 {
+		step = 1
+	}
+	return step
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
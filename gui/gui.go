@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"fmt"
 	"math/rand"
-	"os"
 	"time"
 
 	"gopher-dish/cell"
@@ -12,6 +11,8 @@ import (
 	"gopher-dish/gui/fonts"
 	"gopher-dish/gui/widgets"
 	"gopher-dish/object"
+	"gopher-dish/render"
+	"gopher-dish/utils/genasm"
 	"gopher-dish/world"
 	"gopher-dish/world/worldsaver"
 
@@ -65,14 +66,52 @@ func initGUI() {
 	btnHealth := widgets.NewButton("health", pixel.V(215, 29), pixel.V(60, 30))
 	btnEnergy := widgets.NewButton("energy", pixel.V(280, 29), pixel.V(60, 30))
 	btnAge := widgets.NewButton("age", pixel.V(345, 29), pixel.V(60, 30))
+	btnFood := widgets.NewButton("food", pixel.V(150, 64), pixel.V(60, 30))
+	btnGeneration := widgets.NewButton("gen", pixel.V(215, 64), pixel.V(60, 30))
+	btnGraph := widgets.NewButton("graph", pixel.V(280, 64), pixel.V(60, 30))
+	btnFit := widgets.NewButton("fit", pixel.V(345, 64), pixel.V(60, 30))
+	btnFollow := widgets.NewButton("follow", pixel.V(410, 64), pixel.V(60, 30))
 
 	btnSave := widgets.NewButton("save", pixel.V(425, 29), pixel.V(60, 30))
 	btnRestart := widgets.NewButton("restart", pixel.V(490, 29), pixel.V(60, 30))
+	btnStep := widgets.NewButton("step", pixel.V(570, 29), pixel.V(60, 30))
+	btnTrace := widgets.NewButton("trace", pixel.V(635, 29), pixel.V(60, 30))
+
+	btnSelect := widgets.NewButton("select", pixel.V(715, 29), pixel.V(60, 30))
+	btnPlace := widgets.NewButton("place", pixel.V(780, 29), pixel.V(60, 30))
+	btnBrush := widgets.NewButton("brush", pixel.V(845, 29), pixel.V(60, 30))
+	btnLoad := widgets.NewButton("load", pixel.V(910, 29), pixel.V(60, 30))
+
+	editTools := []struct {
+		tool   Tool
+		button *widgets.Button
+	}{
+		{TOOL_KILL, widgets.NewButton("kill", pixel.V(715, 64), pixel.V(60, 30))},
+		{TOOL_ERASE, widgets.NewButton("erase", pixel.V(780, 64), pixel.V(60, 30))},
+		{TOOL_DRAG, widgets.NewButton("drag", pixel.V(845, 64), pixel.V(60, 30))},
+		{TOOL_ENERGY, widgets.NewButton("energy", pixel.V(910, 64), pixel.V(60, 30))},
+		{TOOL_HEALTH, widgets.NewButton("health", pixel.V(975, 64), pixel.V(60, 30))},
+		{TOOL_SUNLIGHT, widgets.NewButton("sun", pixel.V(1040, 64), pixel.V(60, 30))},
+		{TOOL_MINERALS, widgets.NewButton("mins", pixel.V(1105, 64), pixel.V(60, 30))},
+	}
+	btnUndo := widgets.NewButton("undo", pixel.V(1185, 64), pixel.V(60, 30))
+	btnRedo := widgets.NewButton("redo", pixel.V(1250, 64), pixel.V(60, 30))
+
+	debugPanel := NewDebugPanel(worldToDraw)
+	traceChart := NewTraceChart()
+	graphPanel := NewGraphPanel(worldToDraw)
+	placer := NewPlacer(worldToDraw)
+	editor := NewEditor(worldToDraw, wd)
+	tool := TOOL_SELECT
+	toolText := text.New(pixel.V(990, 40), fonts.RedhatMonoMedium12)
 
 	statusText := text.New(pixel.V(0, 0), fonts.RedhatMonoMedium12)
 
 	viewCanvas := pixelgl.NewCanvas(pixel.R(0, 0, cfg.Bounds.Max.X, cfg.Bounds.Max.Y-24))
 	viewCanvas.SetSmooth(true)
+	// the side panel covers the right of the view
+	wd.SetView(pixel.R(0, 0, viewCanvas.Bounds().W()-320, viewCanvas.Bounds().H()))
+	minimap := NewMinimap(wd)
 
 	sidePanelCanvas := pixelgl.NewCanvas(pixel.R(0, 0, 320, cfg.Bounds.Max.Y-24))
 	sidePanelCanvas.SetFragmentShader(shaderBlur)
@@ -89,19 +128,66 @@ func initGUI() {
 			if win.JustPressed(pixelgl.MouseButtonRight) {
 				moveVec = win.MousePosition()
 			} else {
+				wd.Follow = false
 				wd.Move(win.MousePosition().Sub(moveVec))
 				moveVec = win.MousePosition()
 			}
 		}
 
+		minimap.SetPos(pixel.V(10, win.Bounds().H()-10))
+		if win.Pressed(pixelgl.MouseButtonLeft) && minimap.Contains(win.MousePosition()) {
+			minimap.Jump(win.MousePosition())
+		} else if win.Pressed(pixelgl.MouseButtonLeft) && win.MousePosition().X < win.Bounds().W()-320 && win.MousePosition().Y > 100 {
+			// the view canvas is shifted up by the status bar
+			if pos, ok := wd.PositionAt(win.MousePosition().Sub(pixel.V(0, 12))); ok {
+				justPressed := win.JustPressed(pixelgl.MouseButtonLeft)
+				decrease := win.Pressed(pixelgl.KeyLeftShift) || win.Pressed(pixelgl.KeyRightShift)
+
+				switch {
+				case tool.IsEdit() && justPressed:
+					editor.Press(tool, pos, decrease)
+				case tool.IsEdit():
+					editor.Hold(tool, pos, decrease)
+				case tool != TOOL_SELECT:
+					placer.Apply(tool, pos, justPressed)
+				case justPressed:
+					wd.world.PlacesDrawMux.Lock()
+					obj := wd.world.GetObjectAtPosition(pos)
+					if obj != nil {
+						wd.Selected = obj.GetID()
+					} else {
+						wd.Selected = 0
+					}
+					debugPanel.Select(obj)
+					wd.world.PlacesDrawMux.Unlock()
+				}
+			}
+		}
+		if win.JustReleased(pixelgl.MouseButtonLeft) {
+			editor.Release(tool)
+		}
+
+		if win.Pressed(pixelgl.KeyLeftControl) || win.Pressed(pixelgl.KeyRightControl) {
+			if win.JustPressed(pixelgl.KeyZ) {
+				wd.world.Undo()
+			}
+			if win.JustPressed(pixelgl.KeyY) {
+				wd.world.Redo()
+			}
+		}
+
+		handleCameraKeys(win, wd)
+
 		scrollVec = win.MouseScroll()
 		if scrollVec.Y != 0 {
-			wd.IncZoom(scrollVec.Y, win.Bounds().Center().Sub(win.MousePosition()))
+			// the view canvas is shifted up by the status bar
+			wd.IncZoom(scrollVec.Y, win.MousePosition().Sub(pixel.V(0, 12)))
 		}
 
 		win.Clear(colornames.White)
 
 		viewCanvas.SetBounds(pixel.R(0, 0, win.Bounds().W(), win.Bounds().H()-24))
+		wd.SetView(pixel.R(0, 0, viewCanvas.Bounds().W()-320, viewCanvas.Bounds().H()))
 		viewCanvas.Clear(colornames.White)
 		wd.Draw(viewCanvas)
 		viewCanvas.Draw(win, pixel.IM.Moved(pixel.V(win.Bounds().W()/2, win.Bounds().H()/2)))
@@ -111,11 +197,20 @@ func initGUI() {
 		viewCanvas.Draw(sidePanelCanvas, pixel.IM.Moved(pixel.V(sidePanelCanvas.Bounds().W()-viewCanvas.Bounds().W()/2, viewCanvas.Bounds().H()/2)))
 		sidePanelCanvas.Draw(win, pixel.IM.Moved(pixel.V(win.Bounds().W()-160, win.Bounds().H()/2+12)))
 
+		minimap.Draw(win)
+
 		createStatusBar(statusPanelBg, win.Bounds().Max.X, 24)
 		printStatus(statusText, wd.world, win.Bounds().Max.X, 24)
 		statusPanelBg.Draw(win)
 		statusText.Draw(win, pixel.IM)
 
+		if graphPanel.Visible {
+			graphPanel.Draw(win, pixel.R(win.Bounds().W()-310, 40, win.Bounds().W()-10, win.Bounds().H()-70))
+		} else {
+			debugPanel.Draw(win, pixel.V(win.Bounds().W()-310, win.Bounds().H()-70))
+			traceChart.Draw(win, wd.world.Tracer, pixel.V(win.Bounds().W()-310, 320))
+		}
+
 		btnPlay.SetPos(pixel.V(win.Bounds().W()-310, win.Bounds().H()-46))
 
 		if btnPlay.Draw(win) {
@@ -126,22 +221,74 @@ func initGUI() {
 		}
 
 		if btnNormal.Draw(win) {
-			wd.Filter = W_FILTER_DISABLE
+			wd.Filter = render.FILTER_DISABLE
 		}
 		if btnHealth.Draw(win) {
-			wd.Filter = W_FILTER_HEALTH
+			wd.Filter = render.FILTER_HEALTH
 		}
 		if btnEnergy.Draw(win) {
-			wd.Filter = W_FILTER_ENERGY
+			wd.Filter = render.FILTER_ENERGY
 		}
 		if btnAge.Draw(win) {
-			wd.Filter = W_FILTER_AGE
+			wd.Filter = render.FILTER_AGE
+		}
+		if btnFood.Draw(win) {
+			wd.Filter = render.FILTER_FOOD_TYPE
+		}
+		if btnGeneration.Draw(win) {
+			wd.Filter = render.FILTER_GENERATION
 		}
 
-		if btnSave.Draw(win) {
-			wd.world.Paused = true
+		if btnGraph.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			graphPanel.Visible = !graphPanel.Visible
+		}
+
+		if btnFit.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			wd.Follow = false
+			wd.Fit()
+		}
+		if btnFollow.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			wd.Follow = !wd.Follow && wd.Selected != 0
+		}
+
+		if btnStep.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			debugPanel.Step()
+		}
+
+		if btnTrace.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			toggleTracer(wd.world)
+		}
+
+		if btnSelect.Draw(win) {
+			tool = TOOL_SELECT
+		}
+		if btnPlace.Draw(win) {
+			tool = TOOL_PLACE
+		}
+		if btnBrush.Draw(win) {
+			tool = TOOL_BRUSH
+		}
+		if btnLoad.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			if loadGenomes(placer) && tool == TOOL_SELECT {
+				tool = TOOL_PLACE
+			}
+		}
+		for _, et := range editTools {
+			if et.button.Draw(win) {
+				tool = et.tool
+			}
+		}
+		if btnUndo.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			wd.world.Undo()
+		}
+		if btnRedo.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
+			wd.world.Redo()
+		}
+		printTool(toolText, tool, placer, wd.world)
+		toolText.Draw(win, pixel.IM)
+
+		if btnSave.Draw(win) && win.JustPressed(pixelgl.MouseButtonLeft) {
 			saveWorld(wd.world)
-			wd.world.Paused = false
 		}
 		if btnRestart.Draw(win) {
 			wd.world.Paused = true
@@ -191,18 +338,95 @@ func printStatus(txt *text.Text, w *world.World, width, height float64) {
 	txt.Dot = pixel.V(height/2, height/2-txt.BoundsOf("A").H()/4).Floor()
 	fmt.Fprintf(txt, "FPS: % 5d | Population: % 8d | Day: % 8d | Year: % 8d | Epoch: % 8d | Trend: % 8s",
 		w.Framerate, len(w.Objects), w.Ticks, w.Year, w.Epoch, worldTrendName[w.Trend])
+
+	if len(w.ActiveEvents) > 0 {
+		fmt.Fprint(txt, " | Events:")
+		for _, e := range w.ActiveEvents {
+			fmt.Fprintf(txt, " %s (%d)", e.Type, e.Remaining)
+		}
+	}
+}
+
+// Arrows pan the view, +/- zoom, Home fits the world and F follows the selected cell
+func handleCameraKeys(win *pixelgl.Window, wd *WorldDrawer) {
+	var pan pixel.Vec
+	if win.Pressed(pixelgl.KeyLeft) {
+		pan.X += KeyPanSpeed
+	}
+	if win.Pressed(pixelgl.KeyRight) {
+		pan.X -= KeyPanSpeed
+	}
+	if win.Pressed(pixelgl.KeyUp) {
+		pan.Y -= KeyPanSpeed
+	}
+	if win.Pressed(pixelgl.KeyDown) {
+		pan.Y += KeyPanSpeed
+	}
+	if pan != pixel.ZV {
+		wd.Follow = false
+		wd.Move(pan)
+	}
+
+	center := win.Bounds().Center().Sub(pixel.V(0, 12))
+	if win.JustPressed(pixelgl.KeyEqual) || win.JustPressed(pixelgl.KeyKPAdd) {
+		wd.IncZoom(1, center)
+	}
+	if win.JustPressed(pixelgl.KeyMinus) || win.JustPressed(pixelgl.KeyKPSubtract) {
+		wd.IncZoom(-1, center)
+	}
+	if win.JustPressed(pixelgl.KeyHome) {
+		wd.Follow = false
+		wd.Fit()
+	}
+	if win.JustPressed(pixelgl.KeyF) {
+		wd.Follow = !wd.Follow && wd.Selected != 0
+	}
+}
+
+func toggleTracer(w *world.World) {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	if w.Tracer == nil {
+		w.Tracer = world.NewTracer(genasm.CommandNames())
+	} else {
+		w.Tracer = nil
+	}
+}
+
+func loadGenomes(p *Placer) bool {
+	filename := filepicker.OpenFile("Load genomes")
+	if filename == "" {
+		return false
+	}
+	if err := p.Load(filename); err != nil {
+		fmt.Println("Error load genomes: ", err)
+		return false
+	}
+	fmt.Println("Genomes loaded from: ", filename)
+	return true
+}
+
+func printTool(txt *text.Text, tool Tool, p *Placer, w *world.World) {
+	txt.Clear()
+	txt.Color = pixel.RGB(0.2, 0.2, 0.2)
+	fmt.Fprintf(txt, "Tool: %s", toolName[tool])
+	switch {
+	case tool == TOOL_PLACE || tool == TOOL_BRUSH:
+		fmt.Fprintf(txt, " | %s", p.Status())
+	case tool.IsEdit() && !w.Paused:
+		fmt.Fprint(txt, " | Pause the world to edit")
+	case tool.IsEdit():
+		fmt.Fprint(txt, " | Shift reverses, Ctrl+Z/Ctrl+Y undo/redo")
+	}
 }
 
 func saveWorld(w *world.World) {
 	filename := filepicker.SaveFile("Save world", "world.gdw")
 	if filename != "" {
-		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
-		if err != nil {
-			fmt.Println("Error open file to save: ", err)
+		if err := worldsaver.SaveFile(w, filename); err != nil {
+			fmt.Println("Error save world: ", err)
 			return
 		}
-		defer f.Close()
-		worldsaver.Save(w, f)
 		fmt.Println("World saved at: ", filename)
 	}
 }
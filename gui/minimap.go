@@ -0,0 +1,117 @@
+package gui
+
+import (
+	"math"
+
+	"gopher-dish/object"
+	"gopher-dish/render"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+)
+
+const (
+	MinimapWidth  = 200
+	MinimapHeight = 140
+)
+
+var colorMinimapViewport = pixel.RGB(0.9, 0.1, 0.6)
+
+// Whole world in a corner of the window with the visible part outlined
+type Minimap struct {
+	drawer *WorldDrawer
+	// One pixel per square
+	canvas            *pixelgl.Canvas
+	baseDrawer        *imdraw.IMDraw
+	objectsDrawer     *imdraw.IMDraw
+	frameDrawer       *imdraw.IMDraw
+	lastDrawnRevision uint64
+	bounds            pixel.Rect
+	scale             float64
+}
+
+func NewMinimap(wd *WorldDrawer) *Minimap {
+	m := &Minimap{
+		drawer:        wd,
+		canvas:        pixelgl.NewCanvas(pixel.R(0, 0, float64(wd.world.Width), float64(wd.world.Height))),
+		baseDrawer:    imdraw.New(nil),
+		objectsDrawer: imdraw.New(nil),
+		frameDrawer:   imdraw.New(nil),
+	}
+	m.drawBase()
+	return m
+}
+
+// Place the minimap with its top left corner at the point
+func (m *Minimap) SetPos(pos pixel.Vec) {
+	w, h := float64(m.drawer.world.Width), float64(m.drawer.world.Height)
+	m.scale = math.Min(MinimapWidth/w, MinimapHeight/h)
+	m.bounds = pixel.R(pos.X, pos.Y-h*m.scale, pos.X+w*m.scale, pos.Y)
+}
+
+func (m *Minimap) Contains(v pixel.Vec) bool {
+	return m.bounds.Contains(v)
+}
+
+// Center the view on the world point under the window point
+func (m *Minimap) Jump(v pixel.Vec) {
+	p := v.Sub(m.bounds.Min).Scaled(1 / m.scale)
+	m.drawer.Follow = false
+	m.drawer.LookAt(pixel.V(p.X, float64(m.drawer.world.Height)-p.Y))
+}
+
+func (m *Minimap) Draw(t pixel.Target) {
+	w := m.drawer.world
+
+	m.canvas.Clear(colornames.White)
+	w.PlacesDrawMux.Lock()
+	if w.EnvironmentRevision != m.lastDrawnRevision {
+		m.lastDrawnRevision = w.EnvironmentRevision
+		m.drawBase()
+	}
+	m.objectsDrawer.Clear()
+	for _, o := range w.Objects {
+		pos := o.GetPosition()
+		y := float64(w.Height) - float64(pos.Y)
+		m.objectsDrawer.Color = m.drawer.ObjectColor(o)
+		m.objectsDrawer.Push(pixel.V(float64(pos.X), y), pixel.V(float64(pos.X)+1, y-1))
+		m.objectsDrawer.Rectangle(0)
+	}
+	w.PlacesDrawMux.Unlock()
+	m.baseDrawer.Draw(m.canvas)
+	m.objectsDrawer.Draw(m.canvas)
+	m.canvas.Draw(t, pixel.IM.Scaled(pixel.ZV, m.scale).Moved(m.bounds.Center()))
+
+	// the viewport is in squares from the top left corner
+	viewport := m.drawer.Viewport()
+	visible := pixel.R(
+		m.bounds.Min.X+viewport.Min.X*m.scale, m.bounds.Max.Y-viewport.Max.Y*m.scale,
+		m.bounds.Min.X+viewport.Max.X*m.scale, m.bounds.Max.Y-viewport.Min.Y*m.scale,
+	).Intersect(m.bounds)
+
+	m.frameDrawer.Clear()
+	m.frameDrawer.Color = colorOutline
+	m.frameDrawer.Push(m.bounds.Min, m.bounds.Max)
+	m.frameDrawer.Rectangle(1)
+	if visible.Area() > 0 {
+		m.frameDrawer.Color = colorMinimapViewport
+		m.frameDrawer.Push(visible.Min, visible.Max)
+		m.frameDrawer.Rectangle(1)
+	}
+	m.frameDrawer.Draw(t)
+}
+
+func (m *Minimap) drawBase() {
+	m.baseDrawer.Clear()
+	w := m.drawer.world
+	for x := int32(0); x < int32(w.Width); x++ {
+		for y := int32(0); y < int32(w.Height); y++ {
+			top := float64(w.Height) - float64(y)
+			m.baseDrawer.Color = render.FieldColor(w, object.Position{X: x, Y: y})
+			m.baseDrawer.Push(pixel.V(float64(x), top), pixel.V(float64(x)+1, top-1))
+			m.baseDrawer.Rectangle(0)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/utils/genbank"
+	"gopher-dish/world"
+)
+
+const (
+	BrushRadius = 2
+)
+
+// Spawns genomes from a genome file or copied from a cell at the cursor
+type Placer struct {
+	world   *world.World
+	entries []genbank.Entry
+	next    int
+}
+
+func NewPlacer(w *world.World) *Placer {
+	return &Placer{world: w}
+}
+
+func (p *Placer) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := genbank.Read(f)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no genomes in %s", path)
+	}
+
+	p.entries = entries
+	p.next = 0
+	return nil
+}
+
+func (p *Placer) Copy(c *cell.Cell) {
+	p.entries = []genbank.Entry{{
+		Metadata: genbank.Metadata{Generation: c.Generation, Ancestry: c.ParentsChain},
+		Genome:   c.Genome,
+	}}
+	p.next = 0
+}
+
+// Click on a cell copies its genome, click on an empty square spawns the genome,
+// the brush spawns copies around the cursor while the button is held
+func (p *Placer) Apply(tool Tool, pos object.Position, justPressed bool) {
+	if justPressed {
+		p.world.PlacesDrawMux.Lock()
+		c, ok := p.world.GetObjectAtPosition(pos).(*cell.Cell)
+		p.world.PlacesDrawMux.Unlock()
+		if ok {
+			p.Copy(c)
+			return
+		}
+	}
+
+	if len(p.entries) == 0 {
+		return
+	}
+
+	switch tool {
+	case TOOL_PLACE:
+		if justPressed {
+			p.spawn(pos)
+		}
+	case TOOL_BRUSH:
+		for dx := int32(-BrushRadius); dx <= BrushRadius; dx++ {
+			for dy := int32(-BrushRadius); dy <= BrushRadius; dy++ {
+				if dx*dx+dy*dy <= BrushRadius*BrushRadius {
+					p.spawn(object.Position{X: pos.X + dx, Y: pos.Y + dy})
+				}
+			}
+		}
+	}
+}
+
+// The brush reaches over the world edges, X wraps around and Y is skipped
+func (p *Placer) spawn(pos object.Position) {
+	pos.X = (pos.X + int32(p.world.Width)) % int32(p.world.Width)
+	if pos.Y < 0 || pos.Y >= int32(p.world.Height) {
+		return
+	}
+
+	p.world.PlacesDrawMux.Lock()
+	// cell.New reserves the ID even if the place is taken
+	free := p.world.IsPlaceFree(pos)
+	p.world.PlacesDrawMux.Unlock()
+	if !free {
+		return
+	}
+
+	if _, err := genbank.Inject(p.world, p.entries[p.next], pos); err == nil {
+		p.next = (p.next + 1) % len(p.entries)
+	}
+}
+
+func (p *Placer) Status() string {
+	if len(p.entries) == 0 {
+		return "Click a cell to copy its genome"
+	}
+	return fmt.Sprintf("Genome: %d (%d/%d)", p.entries[p.next].Genome.Hash, p.next+1, len(p.entries))
+}
@@ -0,0 +1,36 @@
+package gui
+
+type Tool byte
+
+// GUI tools list
+const (
+	TOOL_SELECT Tool = iota
+	TOOL_PLACE
+	TOOL_BRUSH
+
+	// Edit tools work only while the world is paused
+	TOOL_KILL
+	TOOL_ERASE
+	TOOL_DRAG
+	TOOL_ENERGY
+	TOOL_HEALTH
+	TOOL_SUNLIGHT
+	TOOL_MINERALS
+)
+
+var toolName = map[Tool]string{
+	TOOL_SELECT:   "select",
+	TOOL_PLACE:    "place",
+	TOOL_BRUSH:    "brush",
+	TOOL_KILL:     "kill",
+	TOOL_ERASE:    "erase",
+	TOOL_DRAG:     "drag",
+	TOOL_ENERGY:   "energy",
+	TOOL_HEALTH:   "health",
+	TOOL_SUNLIGHT: "sunlight",
+	TOOL_MINERALS: "minerals",
+}
+
+func (t Tool) IsEdit() bool {
+	return t >= TOOL_KILL
+}
@@ -0,0 +1,84 @@
+package gui
+
+import (
+	"fmt"
+	"sort"
+
+	"gopher-dish/gui/fonts"
+	"gopher-dish/world"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/text"
+)
+
+const (
+	TraceChartBars  = 16
+	TraceChartWidth = 300
+	// Width of the command name and count columns
+	traceChartLabelWidth = 110
+)
+
+var colorTraceBar = pixel.RGB(0.3, 0.74, 1)
+
+// Bar chart of the most executed commands
+type TraceChart struct {
+	drawer *imdraw.IMDraw
+	text   *text.Text
+}
+
+func NewTraceChart() *TraceChart {
+	return &TraceChart{
+		drawer: imdraw.New(nil),
+		text:   text.New(pixel.V(0, 0), fonts.RedhatMonoMedium12),
+	}
+}
+
+func (tc *TraceChart) Draw(t pixel.Target, tracer *world.Tracer, pos pixel.Vec) {
+	if tracer == nil {
+		return
+	}
+
+	names := tracer.Names()
+	counts := tracer.Commands()
+	order := make([]int, len(counts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+	if len(order) > TraceChartBars {
+		order = order[:TraceChartBars]
+	}
+
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	max := counts[order[0]]
+
+	tc.drawer.Clear()
+	tc.drawer.Color = colorTraceBar
+	tc.text.Clear()
+	tc.text.Color = pixel.RGB(0.2, 0.2, 0.2)
+	tc.text.Orig = pos
+	tc.text.Dot = pos
+	fmt.Fprintf(tc.text, "Executed commands: %d\n", total)
+
+	for i, cmd := range order {
+		if counts[cmd] == 0 {
+			break
+		}
+		// bars are aligned with the text baselines
+		y := pos.Y - float64(i+1)*tc.text.LineHeight
+		fmt.Fprintf(tc.text, "%-5s %5.1f%%\n", names[cmd], float64(counts[cmd])*100/float64(total))
+
+		barWidth := float64(TraceChartWidth-traceChartLabelWidth) * float64(counts[cmd]) / float64(max)
+		tc.drawer.Push(pixel.V(pos.X+traceChartLabelWidth, y-2), pixel.V(pos.X+traceChartLabelWidth+barWidth, y+tc.text.LineHeight*0.6))
+		tc.drawer.Rectangle(0)
+	}
+
+	tc.drawer.Draw(t)
+	tc.text.Draw(t, pixel.IM)
+}
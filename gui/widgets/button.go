@@ -54,6 +54,10 @@ func (b *Button) SetBounds(bounds pixel.Rect) {
 	b.bounds = bounds
 }
 
+func (b *Button) Bounds() pixel.Rect {
+	return b.bounds
+}
+
 func (b *Button) SetPos(pos pixel.Vec) {
 	b.bounds.Max = pos.Add(b.bounds.Size())
 	b.bounds.Min = pos
@@ -2,8 +2,8 @@ package gui
 
 import (
 	"gopher-dish/object"
+	"gopher-dish/render"
 	"gopher-dish/world"
-	"image/color"
 	"math"
 
 	"github.com/faiface/pixel"
@@ -14,51 +14,40 @@ import (
 
 const (
 	DefaultZoomValue = 2
+	MaxZoomValue     = 32
 )
 
 var (
 	colorOutline  = pixel.RGB(0.1, 0.1, 0.1)
-	colorSunlight = pixel.RGB(1, 0.83, 0.3)
-	colorMinerals = pixel.RGB(0.3, 0.74, 1)
-
-	colorObjectLively = pixel.RGB(0.38, 0.58, 0.27)
-	colorObjectDied   = pixel.RGB(0.8, 0.698, 0.729)
-	colorObjectKilled = pixel.RGB(0.702, 0.082, 0.141)
-
-	colorObjectHealthMax = pixel.RGB(0.188, 0.91, 0.118)
-	colorObjectHealthMin = pixel.RGB(0.8, 0.675, 0.161)
-	colorObjectEnergyMax = pixel.RGB(1, 0.91, 0)
-	colorObjectEnergyMin = pixel.RGB(1, 0, 0)
-	colorObjectAgeMax    = pixel.RGB(0, 0.015, 0.52)
-	colorObjectAgeMin    = pixel.RGB(0.1, 0.83, 0.78)
-
-	colorObjectPickable = pixel.RGB(0.41, 0.074, 0.25)
-	colorObjectMovalble = pixel.RGB(0.4, 0.3, 0.47)
-	colorObjectUnknown  = colornames.Magenta
-)
+	colorSunlight = render.ColorSunlight
 
-type WorldFilter byte
+	colorObjectLively = render.ColorObjectLively
+	colorObjectDied   = render.ColorObjectDied
 
-const (
-	W_FILTER_DISABLE    WorldFilter = iota
-	W_FILTER_HEALTH     WorldFilter = iota
-	W_FILTER_ENERGY     WorldFilter = iota
-	W_FILTER_AGE        WorldFilter = iota
-	W_FILTER_FOOD_TYPE  WorldFilter = iota
-	W_FILTER_GENERATION WorldFilter = iota
+	colorObjectBond     = pixel.RGB(0.15, 0.3, 0.1)
+	colorObjectSelected = pixel.RGB(0.9, 0.1, 0.6)
 )
 
 type WorldDrawer struct {
-	Filter WorldFilter
-
-	lastDrawnYear uint64
-	world         *world.World
-	canvas        *pixelgl.Canvas
-	baseDrawer    *imdraw.IMDraw
-	objectsDrawer *imdraw.IMDraw
-	matrix        pixel.Matrix
-	bounds        pixel.Rect
-	zoom          float64
+	render.Palette
+	Selected uint64
+
+	// Rectangle of the area tools
+	Area        [2]object.Position
+	AreaVisible bool
+
+	// Keep the selected object in the center of the view
+	Follow bool
+
+	lastDrawnRevision uint64
+	world             *world.World
+	canvas            *pixelgl.Canvas
+	baseDrawer        *imdraw.IMDraw
+	objectsDrawer     *imdraw.IMDraw
+	matrix            pixel.Matrix
+	bounds            pixel.Rect
+	view              pixel.Rect
+	zoom              float64
 }
 
 func NewWorldDrawer(world *world.World) *WorldDrawer {
@@ -74,31 +63,27 @@ func NewWorldDrawer(world *world.World) *WorldDrawer {
 	return wd
 }
 
-func (wd *WorldDrawer) Move(p pixel.Vec) {
-	wd.matrix = wd.matrix.Moved(p)
+// Change the zoom keeping the point of the view under the anchor in place
+func (wd *WorldDrawer) IncZoom(level float64, anchor pixel.Vec) {
+	wd.setZoom(wd.zoom+math.Round(level), anchor)
 }
 
-func (wd *WorldDrawer) IncZoom(level float64, vec pixel.Vec) {
-	level = math.Round(level)
-	wd.zoom += level
-	if wd.zoom < 1 {
-		wd.zoom = 1
-	} else if wd.zoom > 32 {
-		wd.zoom = 32
+func (wd *WorldDrawer) setZoom(zoom float64, anchor pixel.Vec) {
+	if zoom < 1 {
+		zoom = 1
+	} else if zoom > MaxZoomValue {
+		zoom = MaxZoomValue
 	}
 
-	oldBoundsMax := wd.bounds.Max
-	wd.bounds.Max = pixel.V(float64(wd.world.Width-1)*wd.zoom, float64(wd.world.Height-1)*wd.zoom)
+	// world point under the anchor before and after the zoom
+	before := wd.matrix.Unproject(anchor).Add(wd.canvasCenter(wd.zoom)).Scaled(1 / wd.zoom)
+	wd.zoom = zoom
+	after := wd.matrix.Unproject(anchor).Add(wd.canvasCenter(wd.zoom)).Scaled(1 / wd.zoom)
+	wd.Move(after.Sub(before).Scaled(wd.zoom))
+
+	wd.bounds.Max = pixel.V(float64(wd.world.Width)*wd.zoom, float64(wd.world.Height)*wd.zoom)
 	wd.canvas.SetBounds(wd.bounds)
 	wd.DrawBase()
-
-	if vec.X != 0 && vec.Y != 0 {
-		if level > 0 {
-			wd.Move(vec.ScaledXY(pixel.V(wd.bounds.Max.X/oldBoundsMax.X, wd.bounds.Max.Y/oldBoundsMax.Y)).Scaled(0.25))
-		} else {
-			wd.Move(vec.ScaledXY(pixel.V(-oldBoundsMax.X/wd.bounds.Max.X, -oldBoundsMax.Y/wd.bounds.Max.Y)).Scaled(0.25))
-		}
-	}
 }
 
 func (wd *WorldDrawer) DrawBase() {
@@ -106,13 +91,7 @@ func (wd *WorldDrawer) DrawBase() {
 
 	for x := int32(0); x < int32(wd.world.Width); x++ {
 		for y := int32(0); y < int32(wd.world.Height); y++ {
-			sunlight := wd.world.GetSunlightAtPosition(object.Position{X: x, Y: y})
-			minerals := wd.world.GetMineralsAtPosition(object.Position{X: x, Y: y})
-
-			pixelColor := colorSunlight.Mul(pixel.Alpha(float64(sunlight) / 85))
-			pixelColor = pixelColor.Add(colorMinerals.Mul(pixel.Alpha(float64(minerals) / 85)))
-
-			wd.baseDrawer.Color = pixelColor
+			wd.baseDrawer.Color = render.FieldColor(wd.world, object.Position{X: x, Y: y})
 
 			var posX, posY = float64(x) * wd.zoom, wd.bounds.Max.Y - float64(y)*wd.zoom
 			wd.baseDrawer.Push(pixel.V(posX, posY), pixel.V(posX+wd.zoom, posY-wd.zoom))
@@ -125,36 +104,9 @@ func (wd *WorldDrawer) DrawBase() {
 	wd.baseDrawer.Rectangle(1)
 }
 
-func (wd *WorldDrawer) ComputeObjectColor(obj object.Object) color.Color {
-	switch o := obj.(type) {
-	case object.Lively:
-		if o.IsDied() {
-			if o.IsKilled() {
-				return colorObjectKilled
-			}
-			return colorObjectDied
-		}
-		switch wd.Filter {
-		case W_FILTER_DISABLE:
-			return lerpColor(colorObjectLively, hashToColor(o.GetGenomeHash()), 0.2)
-		case W_FILTER_HEALTH:
-			return lerpColor(colorObjectHealthMin, colorObjectHealthMax, float64(o.GetHealth())/50)
-		case W_FILTER_ENERGY:
-			return lerpColor(colorObjectEnergyMin, colorObjectEnergyMax, float64(o.GetEnergy())/128)
-		case W_FILTER_AGE:
-			return lerpColor(colorObjectAgeMin, colorObjectAgeMax, float64(o.GetAge())/128)
-		}
-	case object.Pickable:
-		return colorObjectPickable
-	case object.Movable:
-		return colorObjectMovalble
-	}
-
-	return colorObjectUnknown
-}
-
 func (wd *WorldDrawer) DrawObjects() {
 	wd.objectsDrawer.Clear()
+	wd.Measure(wd.world)
 	for _, o := range wd.world.Objects {
 		pos := o.GetPosition()
 
@@ -167,23 +119,80 @@ func (wd *WorldDrawer) DrawObjects() {
 			wd.objectsDrawer.Push(pixel.V(posX, posY), pixel.V(posX+wd.zoom, posY-wd.zoom))
 			wd.objectsDrawer.Rectangle(1)
 			// draw cell
-			wd.objectsDrawer.Color = wd.ComputeObjectColor(o)
+			wd.objectsDrawer.Color = wd.ObjectColor(o)
 			wd.objectsDrawer.Push(pixel.V(posX, posY-1), pixel.V(posX+wd.zoom-1, posY-wd.zoom))
 			wd.objectsDrawer.Rectangle(0)
 		} else {
 			// if cells are too small, draw only cells
-			wd.objectsDrawer.Color = wd.ComputeObjectColor(o)
+			wd.objectsDrawer.Color = wd.ObjectColor(o)
 			wd.objectsDrawer.Push(pixel.V(posX, posY), pixel.V(posX+wd.zoom, posY-wd.zoom))
 			wd.objectsDrawer.Rectangle(0)
 		}
 	}
+
+	wd.DrawBonds()
+	wd.DrawSelection()
+}
+
+func (wd *WorldDrawer) DrawBonds() {
+	if wd.zoom < 4 {
+		return
+	}
+
+	wd.objectsDrawer.Color = colorObjectBond
+	for id, o := range wd.world.Objects {
+		b, ok := o.(object.Bondable)
+		if !ok {
+			continue
+		}
+		for _, bondId := range b.GetBonds() {
+			// every bond is stored by both cells, draw it once
+			other := wd.world.GetObject(bondId)
+			if bondId < id || other == nil {
+				continue
+			}
+
+			from, to := o.GetPosition(), other.GetPosition()
+			if math.Abs(float64(from.X-to.X)) > 1 {
+				continue
+			}
+
+			wd.objectsDrawer.Push(
+				pixel.V((float64(from.X)+0.5)*wd.zoom, wd.bounds.Max.Y-(float64(from.Y)+0.5)*wd.zoom),
+				pixel.V((float64(to.X)+0.5)*wd.zoom, wd.bounds.Max.Y-(float64(to.Y)+0.5)*wd.zoom),
+			)
+			wd.objectsDrawer.Line(wd.zoom / 4)
+		}
+	}
+}
+
+func (wd *WorldDrawer) DrawSelection() {
+	if wd.AreaVisible {
+		minX, maxX := math.Min(float64(wd.Area[0].X), float64(wd.Area[1].X)), math.Max(float64(wd.Area[0].X), float64(wd.Area[1].X))
+		minY, maxY := math.Min(float64(wd.Area[0].Y), float64(wd.Area[1].Y)), math.Max(float64(wd.Area[0].Y), float64(wd.Area[1].Y))
+		wd.objectsDrawer.Color = colorObjectSelected
+		wd.objectsDrawer.Push(pixel.V(minX*wd.zoom, wd.bounds.Max.Y-minY*wd.zoom), pixel.V((maxX+1)*wd.zoom, wd.bounds.Max.Y-(maxY+1)*wd.zoom))
+		wd.objectsDrawer.Rectangle(1)
+	}
+
+	o := wd.world.GetObject(wd.Selected)
+	if o == nil {
+		return
+	}
+
+	pos := o.GetPosition()
+	var posX, posY = float64(pos.X) * wd.zoom, wd.bounds.Max.Y - float64(pos.Y)*wd.zoom
+	wd.objectsDrawer.Color = colorObjectSelected
+	wd.objectsDrawer.Push(pixel.V(posX-1, posY+1), pixel.V(posX+wd.zoom+1, posY-wd.zoom-1))
+	wd.objectsDrawer.Rectangle(2)
 }
 
 func (wd *WorldDrawer) Draw(t pixel.Target) {
 	wd.canvas.Clear(colornames.White)
 	wd.world.PlacesDrawMux.Lock()
-	if wd.world.Year != wd.lastDrawnYear && wd.world.Trend != world.TREND_NORMAL {
-		wd.lastDrawnYear = wd.world.Year
+	wd.follow()
+	if wd.world.EnvironmentRevision != wd.lastDrawnRevision {
+		wd.lastDrawnRevision = wd.world.EnvironmentRevision
 		wd.DrawBase()
 	}
 	wd.baseDrawer.Draw(wd.canvas)
@@ -192,28 +201,3 @@ func (wd *WorldDrawer) Draw(t pixel.Target) {
 	wd.objectsDrawer.Draw(wd.canvas)
 	wd.canvas.Draw(t, wd.matrix)
 }
-
-func lerpColor(a pixel.RGBA, b pixel.RGBA, v float64) pixel.RGBA {
-	if v < 0 {
-		v = 0
-	} else if v > 1 {
-		v = 1
-	}
-
-	return pixel.RGBA{
-		R: a.R + (b.R-a.R)*v,
-		G: a.G + (b.G-a.G)*v,
-		B: a.B + (b.B-a.B)*v,
-		A: 1.0,
-	}
-}
-
-func hashToColor(hash uint64) pixel.RGBA {
-	b := float64(hash&0xFFFF) / 0xFFFF
-	return pixel.RGBA{
-		R: (float64((hash>>2)&0xFFFF) / 0xFFFF) * b,
-		G: (float64((hash>>4)&0xFFFF) / 0xFFFF) * b,
-		B: (float64((hash>>6)&0xFFFF) / 0xFFFF) * b,
-		A: 1.0,
-	}
-}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/utils/genasm"
+	"gopher-dish/utils/genbank"
+	"gopher-dish/world"
+	"gopher-dish/world/worldsaver"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const inspectSelectorHelp = `Selectors:
+    N           cell with the id
+    x,y         cell at the position
+    hash:H      genomes with the hash, decimal or 0x hex
+    top:K       K most common genomes of the living cells
+    ancestor    nearest living ancestor of the first cell, diff only
+    ancestor:N  ancestor N generations back, diff only`
+
+// Genome of the living cells found by the hash or top selectors
+type genomeReport struct {
+	Population uint64
+	Generation uint64
+	Ancestry   object.ParentsChain
+	Genome     worldsaver.JSONGenome
+}
+
+// Row of the cells list
+type cellSummary struct {
+	Id         uint64
+	Position   object.Position
+	Generation uint64
+	Age        uint32
+	Health     uint32
+	Energy     uint32
+	Died       bool
+	Hash       uint64
+	// Reachable genome bytes
+	FunctionalSize int
+}
+
+// Print the disassembly of the cells or genomes matching the selector
+func inspect(out io.Writer, w *world.World, selector string, asJSON bool) error {
+	switch {
+	case strings.HasPrefix(selector, "top:"):
+		k, err := strconv.Atoi(strings.TrimPrefix(selector, "top:"))
+		if err != nil || k < 1 {
+			return fmt.Errorf("genomes count of %q must be positive", selector)
+		}
+		entries := genbank.Top(w, k, "")
+		if len(entries) == 0 {
+			return fmt.Errorf("there are no living cells")
+		}
+		return printGenomes(out, entries, asJSON)
+
+	case strings.HasPrefix(selector, "hash:"):
+		hash, err := strconv.ParseUint(strings.TrimPrefix(selector, "hash:"), 0, 64)
+		if err != nil {
+			return fmt.Errorf("bad genome hash %q", selector)
+		}
+		// different genomes may share the hash, all of them are printed
+		var entries []genbank.Entry
+		for _, e := range genbank.Top(w, 0, "") {
+			if e.Genome.Hash == hash {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("there is no living cell with the genome hash %d", hash)
+		}
+		return printGenomes(out, entries, asJSON)
+
+	default:
+		c, err := selectCell(w, selector)
+		if err != nil {
+			return err
+		}
+		return printCell(out, c, asJSON)
+	}
+}
+
+// Cell selected by the id or the position
+func selectCell(w *world.World, selector string) (*cell.Cell, error) {
+	if strings.Contains(selector, ",") {
+		pos, err := parsePosition(selector)
+		if err != nil {
+			return nil, err
+		}
+		if pos.X < 0 || pos.X >= int32(w.Width) || pos.Y < 0 || pos.Y >= int32(w.Height) {
+			return nil, fmt.Errorf("position [%d, %d] is out of the %dx%d world", pos.X, pos.Y, w.Width, w.Height)
+		}
+		c, ok := w.Places[pos.X][pos.Y].(*cell.Cell)
+		if !ok {
+			return nil, fmt.Errorf("there is no cell at [%d, %d]", pos.X, pos.Y)
+		}
+		return c, nil
+	}
+
+	id, err := strconv.ParseUint(selector, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unknown selector %q\n%s", selector, inspectSelectorHelp)
+	}
+	c, ok := w.GetObject(id).(*cell.Cell)
+	if !ok {
+		return nil, fmt.Errorf("there is no cell with id %d", id)
+	}
+	return c, nil
+}
+
+// Nearest living ancestor of the cell, or the one of the depth in the parents chain starting from 1
+func selectAncestor(w *world.World, c *cell.Cell, depth int) (*cell.Cell, error) {
+	if depth < 0 || depth > len(c.ParentsChain) {
+		return nil, fmt.Errorf("ancestor depth must be within 1-%d", len(c.ParentsChain))
+	}
+
+	if depth != 0 {
+		id := c.ParentsChain[depth-1]
+		if id == 0 {
+			return nil, fmt.Errorf("cell %d has no known ancestor %d generations back", c.Name, depth)
+		}
+		ancestor, ok := w.GetObject(id).(*cell.Cell)
+		if !ok || ancestor.Died {
+			return nil, fmt.Errorf("ancestor %d of cell %d is not alive", id, c.Name)
+		}
+		return ancestor, nil
+	}
+
+	for _, id := range c.ParentsChain {
+		if ancestor, ok := w.GetObject(id).(*cell.Cell); ok && id != 0 && !ancestor.Died {
+			return ancestor, nil
+		}
+	}
+	return nil, fmt.Errorf("cell %d has no living ancestors", c.Name)
+}
+
+// Print the unified diff of the genomes, the second selector may be
+// "ancestor" or "ancestor:N" to take the ancestor of the first cell
+func diffCells(out io.Writer, w *world.World, selectorA, selectorB string) error {
+	a, err := selectCell(w, selectorA)
+	if err != nil {
+		return err
+	}
+
+	var b *cell.Cell
+	switch {
+	case selectorB == "ancestor":
+		b, err = selectAncestor(w, a, 0)
+	case strings.HasPrefix(selectorB, "ancestor:"):
+		depth, convErr := strconv.Atoi(strings.TrimPrefix(selectorB, "ancestor:"))
+		if convErr != nil {
+			return fmt.Errorf("bad ancestor depth %q", selectorB)
+		}
+		b, err = selectAncestor(w, a, depth)
+	default:
+		b, err = selectCell(w, selectorB)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the ancestor is the old version of the genome
+	if strings.HasPrefix(selectorB, "ancestor") {
+		a, b = b, a
+	}
+
+	lines := genasm.Diff(a.Genome, b.Genome)
+	diff := genasm.UnifiedDiff(lines, diffName(a), diffName(b), genasm.DiffContext)
+	if diff == "" {
+		fmt.Fprintf(out, "Genomes of cells %d and %d are the same\n", a.Name, b.Name)
+		return nil
+	}
+	fmt.Fprint(out, diff)
+	fmt.Fprintf(out, "# %d lines changed\n", genasm.DiffChanges(lines))
+	return nil
+}
+
+// Write the control flow graph of the cell genome in the DOT language, "-" is the standard output
+func writeCFG(w *world.World, selector, path string) error {
+	c, err := selectCell(w, selector)
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	g := genasm.Analyze(c.Genome, genasm.CellEntries(c)...)
+	return g.WriteDOT(out, fmt.Sprintf("cell %d", c.Name))
+}
+
+func diffName(c *cell.Cell) string {
+	return fmt.Sprintf("cell %d generation %d hash %d", c.Name, c.Generation, c.Genome.Hash)
+}
+
+func parsePosition(s string) (object.Position, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return object.Position{}, fmt.Errorf("bad position %q, expected x,y", s)
+	}
+	x, errX := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 32)
+	y, errY := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 32)
+	if errX != nil || errY != nil {
+		return object.Position{}, fmt.Errorf("bad position %q, expected x,y", s)
+	}
+	return object.Position{X: int32(x), Y: int32(y)}, nil
+}
+
+func printCell(out io.Writer, c *cell.Cell, asJSON bool) error {
+	if asJSON {
+		return printJSON(out, worldsaver.NewJSONCell(c))
+	}
+
+	state := "alive"
+	if c.Died {
+		state = "died"
+	}
+	fmt.Fprintf(out, "# cell %d at [%d, %d], %s\n", c.Name, c.Position.X, c.Position.Y, state)
+	fmt.Fprintf(out, "# generation %d, age %d, health %d, energy %d, genome hash %d\n",
+		c.Generation, c.Age, c.Health, c.Energy, c.Genome.Hash)
+	fmt.Fprintf(out, "# functional size %d of %d bytes\n\n",
+		genasm.Analyze(c.Genome, genasm.CellEntries(c)...).FunctionalSize(), cell.GenomeLength)
+	fmt.Fprint(out, genasm.DisassembleAddressed(c.Genome))
+	return nil
+}
+
+func printGenomes(out io.Writer, entries []genbank.Entry, asJSON bool) error {
+	if asJSON {
+		reports := make([]genomeReport, len(entries))
+		for i, e := range entries {
+			reports[i] = genomeReport{
+				Population: e.Population,
+				Generation: e.Generation,
+				Ancestry:   e.Ancestry,
+				Genome:     worldsaver.NewJSONGenome(e.Genome),
+			}
+		}
+		return printJSON(out, reports)
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(out, "# genome hash %d, population %d, generation %d\n\n", e.Genome.Hash, e.Population, e.Generation)
+		fmt.Fprint(out, genasm.DisassembleAddressed(e.Genome))
+	}
+	return nil
+}
+
+// Print the table of all cells sorted by the id
+func listCells(out io.Writer, w *world.World, asJSON bool) error {
+	rows := make([]cellSummary, 0, len(w.Objects))
+	for _, obj := range w.Objects {
+		c, ok := obj.(*cell.Cell)
+		if !ok {
+			continue
+		}
+		rows = append(rows, cellSummary{
+			Id:         c.Name,
+			Position:   c.Position,
+			Generation: c.Generation,
+			Age:        c.Age,
+			Health:     c.Health,
+			Energy:     c.Energy,
+			Died:       c.Died,
+			Hash:       c.Genome.Hash,
+
+			FunctionalSize: genasm.Analyze(c.Genome, genasm.CellEntries(c)...).FunctionalSize(),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Id < rows[j].Id })
+
+	if asJSON {
+		return printJSON(out, rows)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "ID\tX\tY\tGEN\tAGE\tHEALTH\tENERGY\tSTATE\tHASH\tSIZE\t")
+	for _, r := range rows {
+		state := "alive"
+		if r.Died {
+			state = "died"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%d\t%d\t\n",
+			r.Id, r.Position.X, r.Position.Y, r.Generation, r.Age, r.Health, r.Energy, state, r.Hash, r.FunctionalSize)
+	}
+	return tw.Flush()
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
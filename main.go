@@ -1,17 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"gopher-dish/api"
 	"gopher-dish/cell"
-	"gopher-dish/gui"
 	"gopher-dish/object"
+	"gopher-dish/render"
 	"gopher-dish/utils"
 	"gopher-dish/utils/genasm"
+	"gopher-dish/utils/genbank"
 	"gopher-dish/world"
 	"gopher-dish/world/worldsaver"
 	"math/rand"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -20,9 +26,38 @@ const (
 	UITickInterval    = 33 * time.Millisecond
 )
 
+type injection struct {
+	entries []genbank.Entry
+	// positions are parsed once the world size is known
+	at string
+}
+
+// Consume the next argument if it is the flag
+func nextArgIs(i *utils.Iterator, flag string) bool {
+	if int(*i) < len(os.Args) && os.Args[*i] == flag {
+		i.Inc()
+		return true
+	}
+	return false
+}
+
 func main() {
 	var baseWorld *world.World
+	// Config file is applied over the config of the loaded or generated world
+	var baseConfig []byte
+	var statsStream *world.StatsStream
+	var trace bool
+	var worldPath string
+	var injections []injection
 	var seed int64
+	var headless bool
+	var maxTicks uint64
+	var httpAddr string
+	var saveDir string
+	var streamEvery uint64 = api.DefaultStreamEvery
+	renderer := render.NewRenderer(render.FILTER_DISABLE, render.DefaultScale)
+	recorder := &render.Recorder{Renderer: renderer}
+	autosaver := &worldsaver.Autosaver{Keep: worldsaver.DefaultKeep}
 
 	var i utils.Iterator
 	for int(i) < len(os.Args) {
@@ -54,6 +89,88 @@ func main() {
 			if err != nil {
 				panic(err)
 			}
+			worldPath = path
+
+		case "--resume":
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing checkpoints directory")
+				os.Exit(22)
+			}
+			dir := os.Args[i.Inc()]
+
+			w, path, err := worldsaver.Resume(dir)
+			if err != nil {
+				fmt.Println("Can't resume:", err)
+				os.Exit(22)
+			}
+			fmt.Println("Resumed from", path)
+			baseWorld = w
+			worldPath = path
+
+		case "--autosave":
+			if len(os.Args) < int(i)+2 {
+				fmt.Println("Missing autosave directory or interval")
+				os.Exit(22)
+			}
+			autosaver.Dir = os.Args[i.Inc()]
+			// Interval in ticks, or in years with the "y" suffix
+			every := os.Args[i.Inc()]
+			years := strings.HasSuffix(every, "y")
+			num, err := strconv.ParseUint(strings.TrimSuffix(every, "y"), 10, 64)
+			if err != nil || num == 0 {
+				fmt.Println("Autosave interval must be positive ticks or years like 5y")
+				os.Exit(22)
+			}
+			if years {
+				autosaver.EveryYears = num
+			} else {
+				autosaver.EveryTicks = num
+			}
+
+		case "--autosave-keep":
+			num, err := strconv.Atoi(os.Args[i.Inc()])
+			if err != nil {
+				panic(err)
+			}
+			if num < 1 {
+				fmt.Println("Count of kept checkpoints must be positive")
+				os.Exit(22)
+			}
+			autosaver.Keep = num
+
+		case "-c", "--config":
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing path to the config file")
+				os.Exit(22)
+			}
+			path := os.Args[i.Inc()]
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				panic(err)
+			}
+			if _, err := world.LoadConfig(bytes.NewReader(data)); err != nil {
+				panic(err)
+			}
+			baseConfig = data
+
+		case "--stats":
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing path to the stats file")
+				os.Exit(22)
+			}
+			path := os.Args[i.Inc()]
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+
+			statsStream = world.NewStatsStream(f)
+
+		case "--trace":
+			trace = true
 
 		case "-i", "--info":
 			if baseWorld == nil {
@@ -69,32 +186,237 @@ func main() {
 			fmt.Printf("    ID counter: %d\n", baseWorld.ObjectsIdCounter)
 			fmt.Printf("    Population: %d\n", len(baseWorld.Objects))
 
+		// Selector is followed by the optional --json flag
 		case "-d", "--disassembly":
 			if baseWorld == nil {
 				fmt.Println("You need to load the world first by '-w' or '--world' command")
 				os.Exit(22)
 			}
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing cell selector")
+				fmt.Println(inspectSelectorHelp)
+				os.Exit(22)
+			}
+			selector := os.Args[i.Inc()]
+
+			if err := inspect(os.Stdout, baseWorld, selector, nextArgIs(&i, "--json")); err != nil {
+				fmt.Println(err)
+				os.Exit(22)
+			}
+
+		case "--diff":
+			if baseWorld == nil {
+				fmt.Println("You need to load the world first by '-w' or '--world' command")
+				os.Exit(22)
+			}
+			if len(os.Args) < int(i)+2 {
+				fmt.Println("Missing cell selectors")
+				fmt.Println(inspectSelectorHelp)
+				os.Exit(22)
+			}
+			selectorA, selectorB := os.Args[i.Inc()], os.Args[i.Inc()]
+
+			if err := diffCells(os.Stdout, baseWorld, selectorA, selectorB); err != nil {
+				fmt.Println(err)
+				os.Exit(22)
+			}
+
+		case "--cfg":
+			if baseWorld == nil {
+				fmt.Println("You need to load the world first by '-w' or '--world' command")
+				os.Exit(22)
+			}
+			if len(os.Args) < int(i)+2 {
+				fmt.Println("Missing cell selector or path to the DOT file")
+				os.Exit(22)
+			}
+			selector, path := os.Args[i.Inc()], os.Args[i.Inc()]
+
+			if err := writeCFG(baseWorld, selector, path); err != nil {
+				fmt.Println(err)
+				os.Exit(22)
+			}
+
+		case "--list":
+			if baseWorld == nil {
+				fmt.Println("You need to load the world first by '-w' or '--world' command")
+				os.Exit(22)
+			}
+
+			if err := listCells(os.Stdout, baseWorld, nextArgIs(&i, "--json")); err != nil {
+				panic(err)
+			}
+
+		case "--debug":
+			if baseWorld == nil {
+				fmt.Println("You need to load the world first by '-w' or '--world' command")
+				os.Exit(22)
+			}
 
 			num, err := strconv.ParseUint(os.Args[i.Inc()], 10, 64)
 			if err != nil {
 				panic(err)
 			}
 
-			var (
-				cellObj *cell.Cell
-				ok      bool
-			)
+			cellObj, ok := baseWorld.GetObject(num).(*cell.Cell)
+			if !ok {
+				fmt.Printf("There is no cell with id %d\n", num)
+				os.Exit(22)
+			}
 
-			cellid := num % uint64(baseWorld.ObjectsIdCounter)
-			cellObj, ok = baseWorld.GetObject(cellid).(*cell.Cell)
+			baseWorld.EnterStepping()
+			runDebugger(cellObj, os.Stdin, os.Stdout)
+			os.Exit(0)
 
-			for !ok {
-				cellid = rand.Uint64() % uint64(baseWorld.ObjectsIdCounter)
-				cellObj, ok = baseWorld.GetObject(cellid).(*cell.Cell)
+		case "--export":
+			if baseWorld == nil {
+				fmt.Println("You need to load the world first by '-w' or '--world' command")
+				os.Exit(22)
+			}
+			if len(os.Args) < int(i)+2 {
+				fmt.Println("Missing genomes count or path to the genome file")
+				os.Exit(22)
 			}
 
-			code := genasm.Disassemble(cellObj.Genome)
-			fmt.Println(code)
+			k, err := strconv.Atoi(os.Args[i.Inc()])
+			if err != nil {
+				panic(err)
+			}
+			path := os.Args[i.Inc()]
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+			if err != nil {
+				panic(err)
+			}
+			entries := genbank.Top(baseWorld, k, worldPath)
+			err = genbank.Write(f, entries, genbank.FormatByPath(path))
+			f.Close()
+			if err != nil {
+				panic(err)
+			}
+			fmt.Printf("Exported %d genomes to %s\n", len(entries), path)
+
+		case "--export-world":
+			if baseWorld == nil {
+				fmt.Println("You need to load the world first by '-w' or '--world' command")
+				os.Exit(22)
+			}
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing path to the exported world file")
+				os.Exit(22)
+			}
+			path := os.Args[i.Inc()]
+
+			if err := worldsaver.SaveFile(baseWorld, path); err != nil {
+				panic(err)
+			}
+			fmt.Printf("Exported the world to %s\n", path)
+
+		case "--inject":
+			if len(os.Args) < int(i)+2 {
+				fmt.Println("Missing path to the genome file or positions")
+				os.Exit(22)
+			}
+			path := os.Args[i.Inc()]
+
+			f, err := os.Open(path)
+			if err != nil {
+				panic(err)
+			}
+			entries, err := genbank.Read(f)
+			f.Close()
+			if err != nil {
+				panic(err)
+			}
+			if len(entries) == 0 {
+				fmt.Println("No genomes in", path)
+				os.Exit(22)
+			}
+
+			injections = append(injections, injection{entries, os.Args[i.Inc()]})
+
+		case "--headless":
+			headless = true
+
+		case "--ticks":
+			num, err := strconv.ParseUint(os.Args[i.Inc()], 10, 64)
+			if err != nil {
+				panic(err)
+			}
+			maxTicks = num
+
+		case "--http":
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing address of the HTTP server")
+				os.Exit(22)
+			}
+			httpAddr = os.Args[i.Inc()]
+
+		case "--save-dir":
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing directory of the HTTP API saves")
+				os.Exit(22)
+			}
+			saveDir = os.Args[i.Inc()]
+
+		case "--stream-every":
+			num, err := strconv.ParseUint(os.Args[i.Inc()], 10, 64)
+			if err != nil {
+				panic(err)
+			}
+			if num == 0 {
+				fmt.Println("Stream interval must be positive")
+				os.Exit(22)
+			}
+			streamEvery = num
+
+		case "--png":
+			if len(os.Args) < int(i)+2 {
+				fmt.Println("Missing snapshots directory or interval")
+				os.Exit(22)
+			}
+			recorder.SnapshotDir = os.Args[i.Inc()]
+			num, err := strconv.ParseUint(os.Args[i.Inc()], 10, 64)
+			if err != nil {
+				panic(err)
+			}
+			recorder.SnapshotEvery = num
+
+		case "--gif":
+			if len(os.Args) < int(i)+2 {
+				fmt.Println("Missing path to the timelapse file or interval")
+				os.Exit(22)
+			}
+			recorder.TimelapsePath = os.Args[i.Inc()]
+			num, err := strconv.ParseUint(os.Args[i.Inc()], 10, 64)
+			if err != nil {
+				panic(err)
+			}
+			recorder.TimelapseEvery = num
+
+		case "--render-filter":
+			if len(os.Args) < int(i)+1 {
+				fmt.Println("Missing filter name")
+				os.Exit(22)
+			}
+			name := os.Args[i.Inc()]
+			filter, ok := render.FilterByName(name)
+			if !ok {
+				fmt.Println("Unknown filter", name)
+				os.Exit(22)
+			}
+			renderer.Filter = filter
+
+		case "--render-scale":
+			num, err := strconv.Atoi(os.Args[i.Inc()])
+			if err != nil {
+				panic(err)
+			}
+			if num < 1 {
+				fmt.Println("Render scale must be positive")
+				os.Exit(22)
+			}
+			renderer.Scale = num
 
 		case "-q", "--exit":
 			os.Exit(0)
@@ -124,11 +446,82 @@ func main() {
 		}
 	}
 
+	// Genomes are placed round-robin, so one genome can be seeded at many positions
+	for _, inj := range injections {
+		positions, err := genbank.ParsePositions(inj.at, int32(baseWorld.Width), int32(baseWorld.Height))
+		if err != nil {
+			fmt.Println("Can't inject genomes:", err)
+			os.Exit(22)
+		}
+		for n, pos := range positions {
+			if _, err := genbank.Inject(baseWorld, inj.entries[n%len(inj.entries)], pos); err != nil {
+				fmt.Println("Can't inject genome:", err)
+			}
+		}
+	}
+
+	if baseConfig != nil {
+		if err := baseWorld.Config.Apply(bytes.NewReader(baseConfig)); err != nil {
+			fmt.Println("Can't apply the config:", err)
+			os.Exit(22)
+		}
+	}
+	baseWorld.Stats = statsStream
+	baseWorld.Analyzer = genasm.FunctionalSizer{}
+	if trace {
+		baseWorld.Tracer = world.NewTracer(genasm.CommandNames())
+	}
+
+	// Unpaused before the loop and the server start, both read the flag
+	headless = headless || !windowAvailable
+	if headless {
+		baseWorld.Paused = false
+	}
+
+	if httpAddr != "" {
+		server := api.New(baseWorld)
+		server.StreamEvery = streamEvery
+		if saveDir != "" {
+			server.SaveDir = saveDir
+		}
+		go func() {
+			if err := server.ListenAndServe(httpAddr); err != nil {
+				fmt.Println("HTTP server stopped:", err)
+			}
+		}()
+	}
+
+	if autosaver.Dir != "" {
+		autosaver.Start(baseWorld)
+	}
+
+	done := make(chan struct{})
 	go func() {
-		for {
+		defer close(done)
+		for maxTicks == 0 || baseWorld.Ticks < maxTicks {
 			baseWorld.Handle()
+			if err := recorder.Capture(baseWorld); err != nil {
+				fmt.Println("Can't render the world:", err)
+			}
+			if autosaver.Dir != "" && !baseWorld.Paused {
+				autosaver.Check(baseWorld)
+			}
 		}
 	}()
 
-	gui.Run(UITickInterval, baseWorld)
+	if headless {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-done:
+		case <-interrupt:
+		}
+	} else {
+		runWindow(baseWorld)
+	}
+
+	autosaver.Wait()
+	if err := recorder.Close(); err != nil {
+		fmt.Println("Can't write the timelapse:", err)
+	}
 }
@@ -0,0 +1,8 @@
+package object
+
+type Bondable interface {
+	Object
+	GetBonds() []uint64
+	AddBond(id uint64) bool
+	RemoveBond(id uint64) bool
+}
@@ -0,0 +1,19 @@
+package object
+
+// Food sources list
+const (
+	FOOD_SUNLIGHT = iota
+	FOOD_MEAT
+	FOOD_SHARED
+	FOOD_BAGAGE
+
+	FOOD_ENUM_SIZE
+)
+
+// Energy got from every food source during the life
+type Diet [FOOD_ENUM_SIZE]uint32
+
+type Eater interface {
+	Object
+	GetDiet() Diet
+}
@@ -0,0 +1,13 @@
+package object
+
+// Editable objects can be changed by the world editing tools
+type Editable interface {
+	Movable
+
+	// Closure bringing the object back to its current state
+	Snapshot() func()
+
+	SetPosition(pos Position)
+	SetEnergy(energy uint32)
+	SetHealth(health uint32)
+}
@@ -6,22 +6,35 @@ const (
 
 type ParentsChain [RelatedDepth]uint64
 
+type DeathCause byte
+
+// Death causes list
+const (
+	DEATH_STARVATION DeathCause = iota
+	DEATH_BITE
+	DEATH_WOUNDS
+	DEATH_EVENT
+	DEATH_EDIT
+	DEATH_CAUSE_ENUM_SIZE
+)
+
 type Lively interface {
 	Object
 
 	GetAge() uint32
+	GetGeneration() uint64
 	GetGenomeHash() uint64
 	GetParentsChain() ParentsChain
 
-	GetHealth() byte
-	LoseHealth(health byte) bool
-	HealHealth(health byte) bool
+	GetHealth() uint32
+	LoseHealth(health uint32, cause DeathCause) bool
+	HealHealth(health uint32) bool
 
 	IsDied() bool
 	IsKilled() bool
 	IsReleated(another Lively) bool
 
 	Reproduce(dir Rotation) bool
-	Bite(strength byte) byte
-	Die() bool
+	Bite(strength uint32) uint32
+	Die(cause DeathCause) bool
 }
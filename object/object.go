@@ -14,7 +14,7 @@ type Object interface {
 	Handle(yearChanged, epochChanged bool)
 	Save(writer io.Writer) error
 
-	GetEnergy() byte
-	SpendEnergy(energy byte) bool
-	IncreaseEnergy(energy byte) bool
+	GetEnergy() uint32
+	SpendEnergy(energy uint32) bool
+	IncreaseEnergy(energy uint32) bool
 }
@@ -0,0 +1,211 @@
+package render
+
+import (
+	"math"
+
+	"gopher-dish/object"
+	"gopher-dish/world"
+
+	"github.com/faiface/pixel"
+	"golang.org/x/image/colornames"
+)
+
+var (
+	ColorSunlight = pixel.RGB(1, 0.83, 0.3)
+	ColorMinerals = pixel.RGB(0.3, 0.74, 1)
+
+	ColorObjectLively = pixel.RGB(0.38, 0.58, 0.27)
+	ColorObjectDied   = pixel.RGB(0.8, 0.698, 0.729)
+	ColorObjectKilled = pixel.RGB(0.702, 0.082, 0.141)
+
+	colorObjectHealthMax = pixel.RGB(0.188, 0.91, 0.118)
+	colorObjectHealthMin = pixel.RGB(0.8, 0.675, 0.161)
+	colorObjectEnergyMax = pixel.RGB(1, 0.91, 0)
+	colorObjectEnergyMin = pixel.RGB(1, 0, 0)
+	colorObjectAgeMax    = pixel.RGB(0, 0.015, 0.52)
+	colorObjectAgeMin    = pixel.RGB(0.1, 0.83, 0.78)
+	colorObjectNoDiet    = pixel.RGB(0.6, 0.6, 0.6)
+
+	// Perceptually uniform ramp for the generation filter
+	rampGeneration = []pixel.RGBA{
+		pixel.RGB(0.27, 0, 0.33),
+		pixel.RGB(0.23, 0.32, 0.55),
+		pixel.RGB(0.13, 0.57, 0.55),
+		pixel.RGB(0.37, 0.79, 0.38),
+		pixel.RGB(0.99, 0.91, 0.14),
+	}
+
+	colorObjectPickable = pixel.RGB(0.41, 0.074, 0.25)
+	colorObjectMovalble = pixel.RGB(0.4, 0.3, 0.47)
+	colorObjectUnknown  = pixel.ToRGBA(colornames.Magenta)
+)
+
+type Filter byte
+
+const (
+	FILTER_DISABLE    Filter = iota
+	FILTER_HEALTH     Filter = iota
+	FILTER_ENERGY     Filter = iota
+	FILTER_AGE        Filter = iota
+	FILTER_FOOD_TYPE  Filter = iota
+	FILTER_GENERATION Filter = iota
+	FILTER_COUNT      Filter = iota
+)
+
+var filterNames = map[Filter]string{
+	FILTER_DISABLE:    "normal",
+	FILTER_HEALTH:     "health",
+	FILTER_ENERGY:     "energy",
+	FILTER_AGE:        "age",
+	FILTER_FOOD_TYPE:  "food",
+	FILTER_GENERATION: "gen",
+}
+
+func (f Filter) String() string {
+	return filterNames[f]
+}
+
+func FilterByName(name string) (Filter, bool) {
+	for f, n := range filterNames {
+		if n == name {
+			return f, true
+		}
+	}
+	return FILTER_COUNT, false
+}
+
+// Ranges of the living population the filters are normalized by
+type populationRange struct {
+	maxHealth, maxEnergy, maxAge uint32
+	minGeneration, maxGeneration uint64
+}
+
+// Object colours of the filter, shared by the window and the offscreen renderer
+type Palette struct {
+	Filter     Filter
+	population populationRange
+}
+
+// Measure the ranges of the current population, called under the world lock
+func (p *Palette) Measure(w *world.World) {
+	r := populationRange{minGeneration: math.MaxUint64}
+	for _, obj := range w.Objects {
+		o, ok := obj.(object.Lively)
+		if !ok || o.IsDied() {
+			continue
+		}
+		if o.GetHealth() > r.maxHealth {
+			r.maxHealth = o.GetHealth()
+		}
+		if o.GetEnergy() > r.maxEnergy {
+			r.maxEnergy = o.GetEnergy()
+		}
+		if o.GetAge() > r.maxAge {
+			r.maxAge = o.GetAge()
+		}
+		if o.GetGeneration() < r.minGeneration {
+			r.minGeneration = o.GetGeneration()
+		}
+		if o.GetGeneration() > r.maxGeneration {
+			r.maxGeneration = o.GetGeneration()
+		}
+	}
+	p.population = r
+}
+
+func (p *Palette) ObjectColor(obj object.Object) pixel.RGBA {
+	switch o := obj.(type) {
+	case object.Lively:
+		if o.IsDied() {
+			if o.IsKilled() {
+				return ColorObjectKilled
+			}
+			return ColorObjectDied
+		}
+		switch p.Filter {
+		case FILTER_DISABLE:
+			return lerpColor(ColorObjectLively, hashToColor(o.GetGenomeHash()), 0.2)
+		case FILTER_HEALTH:
+			return lerpColor(colorObjectHealthMin, colorObjectHealthMax, normalize(float64(o.GetHealth()), 0, float64(p.population.maxHealth)))
+		case FILTER_ENERGY:
+			return lerpColor(colorObjectEnergyMin, colorObjectEnergyMax, normalize(float64(o.GetEnergy()), 0, float64(p.population.maxEnergy)))
+		case FILTER_AGE:
+			return lerpColor(colorObjectAgeMin, colorObjectAgeMax, normalize(float64(o.GetAge()), 0, float64(p.population.maxAge)))
+		case FILTER_FOOD_TYPE:
+			if eater, ok := obj.(object.Eater); ok {
+				return dietColor(eater.GetDiet())
+			}
+			return colorObjectNoDiet
+		case FILTER_GENERATION:
+			return rampColor(rampGeneration, normalize(float64(o.GetGeneration()), float64(p.population.minGeneration), float64(p.population.maxGeneration)))
+		}
+	case object.Pickable:
+		return colorObjectPickable
+	case object.Movable:
+		return colorObjectMovalble
+	}
+
+	return colorObjectUnknown
+}
+
+// Premultiplied colour of the sunlight and minerals under the objects
+func FieldColor(w *world.World, pos object.Position) pixel.RGBA {
+	sunlight := w.GetSunlightAtPosition(pos)
+	minerals := w.GetMineralsAtPosition(pos)
+
+	pixelColor := ColorSunlight.Mul(pixel.Alpha(float64(sunlight) / 85))
+	return pixelColor.Add(ColorMinerals.Mul(pixel.Alpha(float64(minerals) / 85)))
+}
+
+// Sunlight is green, meat is red, shared and bag energy is blue
+func dietColor(diet object.Diet) pixel.RGBA {
+	meat := float64(diet[object.FOOD_MEAT])
+	sun := float64(diet[object.FOOD_SUNLIGHT])
+	other := float64(diet[object.FOOD_SHARED]) + float64(diet[object.FOOD_BAGAGE])
+	total := meat + sun + other
+	if total == 0 {
+		return colorObjectNoDiet
+	}
+	return pixel.RGB(meat/total, sun/total, other/total)
+}
+
+func normalize(value, min, max float64) float64 {
+	if max <= min {
+		return 0
+	}
+	return (value - min) / (max - min)
+}
+
+func rampColor(stops []pixel.RGBA, v float64) pixel.RGBA {
+	v = math.Max(0, math.Min(1, v)) * float64(len(stops)-1)
+	i := int(v)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	return lerpColor(stops[i], stops[i+1], v-float64(i))
+}
+
+func lerpColor(a pixel.RGBA, b pixel.RGBA, v float64) pixel.RGBA {
+	if v < 0 {
+		v = 0
+	} else if v > 1 {
+		v = 1
+	}
+
+	return pixel.RGBA{
+		R: a.R + (b.R-a.R)*v,
+		G: a.G + (b.G-a.G)*v,
+		B: a.B + (b.B-a.B)*v,
+		A: 1.0,
+	}
+}
+
+func hashToColor(hash uint64) pixel.RGBA {
+	b := float64(hash&0xFFFF) / 0xFFFF
+	return pixel.RGBA{
+		R: (float64((hash>>2)&0xFFFF) / 0xFFFF) * b,
+		G: (float64((hash>>4)&0xFFFF) / 0xFFFF) * b,
+		B: (float64((hash>>6)&0xFFFF) / 0xFFFF) * b,
+		A: 1.0,
+	}
+}
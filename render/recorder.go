@@ -0,0 +1,106 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopher-dish/world"
+)
+
+const (
+	// Hundredths of a second between the timelapse frames
+	TimelapseFrameDelay = 10
+)
+
+// Writes PNG snapshots and timelapse frames every given number of ticks,
+// the timelapse is kept in memory until Close
+type Recorder struct {
+	Renderer *Renderer
+
+	SnapshotDir   string
+	SnapshotEvery uint64
+
+	TimelapsePath  string
+	TimelapseEvery uint64
+
+	mux      sync.Mutex
+	frames   []*image.Paletted
+	lastTick uint64
+}
+
+// Called after every world tick, does nothing if the tick did not advance
+func (r *Recorder) Capture(w *world.World) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	tick := w.Ticks
+	if tick == r.lastTick {
+		return nil
+	}
+	r.lastTick = tick
+
+	snapshot := r.SnapshotEvery != 0 && tick%r.SnapshotEvery == 0
+	timelapse := r.TimelapseEvery != 0 && r.TimelapsePath != "" && tick%r.TimelapseEvery == 0
+	if !snapshot && !timelapse {
+		return nil
+	}
+
+	img := r.Renderer.Render(w)
+	if timelapse {
+		frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), img, image.Point{}, draw.Src)
+		r.frames = append(r.frames, frame)
+	}
+	if snapshot {
+		return r.writeSnapshot(img, tick)
+	}
+	return nil
+}
+
+// Write the timelapse collected so far
+func (r *Recorder) Close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.TimelapsePath == "" || len(r.frames) == 0 {
+		return nil
+	}
+
+	anim := gif.GIF{Image: r.frames, Delay: make([]int, len(r.frames))}
+	for i := range anim.Delay {
+		anim.Delay[i] = TimelapseFrameDelay
+	}
+
+	f, err := os.Create(r.TimelapsePath)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, &anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (r *Recorder) writeSnapshot(img image.Image, tick uint64) error {
+	if err := os.MkdirAll(r.SnapshotDir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(r.SnapshotDir, fmt.Sprintf("tick-%010d.png", tick)))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gopher-dish/object"
+	"gopher-dish/world"
+
+	"github.com/faiface/pixel"
+)
+
+const (
+	DefaultScale = 2
+)
+
+// Draws the world into an image without a GPU, every square takes Scale pixels
+type Renderer struct {
+	Palette
+	Scale int
+}
+
+func NewRenderer(filter Filter, scale int) *Renderer {
+	if scale < 1 {
+		scale = DefaultScale
+	}
+	return &Renderer{Palette: Palette{Filter: filter}, Scale: scale}
+}
+
+func (r *Renderer) Render(w *world.World) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(w.Width)*r.Scale, int(w.Height)*r.Scale))
+
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+
+	for x := int32(0); x < int32(w.Width); x++ {
+		for y := int32(0); y < int32(w.Height); y++ {
+			pos := object.Position{X: x, Y: y}
+			r.fill(img, pos, FieldRGBA(w, pos))
+		}
+	}
+
+	r.Measure(w)
+	for _, o := range w.Objects {
+		r.fill(img, o.GetPosition(), r.ObjectRGBA(o))
+	}
+	return img
+}
+
+func (r *Renderer) fill(img *image.RGBA, pos object.Position, c color.RGBA) {
+	for dx := 0; dx < r.Scale; dx++ {
+		for dy := 0; dy < r.Scale; dy++ {
+			img.SetRGBA(int(pos.X)*r.Scale+dx, int(pos.Y)*r.Scale+dy, c)
+		}
+	}
+}
+
+// Opaque object colour for the image formats
+func (p *Palette) ObjectRGBA(obj object.Object) color.RGBA {
+	return toRGBA(p.ObjectColor(obj))
+}
+
+// Field colour over the white background
+func FieldRGBA(w *world.World, pos object.Position) color.RGBA {
+	return overWhite(FieldColor(w, pos))
+}
+
+// Blend the premultiplied colour over the white background like the window canvas
+func overWhite(c pixel.RGBA) color.RGBA {
+	a := math.Min(c.A, 1)
+	return toRGBA(pixel.RGB(c.R+1-a, c.G+1-a, c.B+1-a))
+}
+
+func toRGBA(c pixel.RGBA) color.RGBA {
+	channel := func(v float64) uint8 {
+		return uint8(math.Max(0, math.Min(1, v))*255 + 0.5)
+	}
+	return color.RGBA{R: channel(c.R), G: channel(c.G), B: channel(c.B), A: 255}
+}
@@ -0,0 +1,175 @@
+package genasm
+
+import (
+	"fmt"
+	"gopher-dish/cell"
+	"strconv"
+	"strings"
+)
+
+// Assemble the genome from the listing produced by Disassemble.
+// Lines starting with '#' are comments, missing traits take the base values
+// and the code shorter than the genome is padded with nop
+func Assemble(code string) (genome cell.Genome, err error) {
+	var bytes [cell.GenomeLength]cell.Command
+	traits := cell.BaseTraits()
+	addr := 0
+
+	for n, stmt := range strings.Split(stripComments(code), ";") {
+		fields := strings.Fields(stmt)
+		if len(fields) == 0 {
+			continue
+		}
+		// commands of the listing may wrap around the genome end, but can't start after it
+		if addr >= cell.GenomeLength {
+			return genome, fmt.Errorf("statement %d: code is longer than %d bytes", n+1, cell.GenomeLength)
+		}
+
+		name := fields[0]
+		args := splitArgs(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(stmt), name)))
+
+		var emit []cell.Command
+		if strings.HasPrefix(name, ".") {
+			emit, err = assembleDirective(name[1:], args, &traits)
+		} else {
+			emit, err = assembleCommand(name, args)
+		}
+		if err != nil {
+			return genome, fmt.Errorf("statement %d: %w", n+1, err)
+		}
+
+		for _, b := range emit {
+			if addr < cell.GenomeLength {
+				bytes[addr] = b
+			}
+			addr++
+		}
+	}
+
+	return cell.NewGenome(bytes, traits), nil
+}
+
+func assembleDirective(name string, args []string, traits *cell.Traits) ([]cell.Command, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf(".%s expects 1 argument, got %d", name, len(args))
+	}
+	value, err := parseConst(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "byte" {
+		return []cell.Command{value}, nil
+	}
+	for i, traitName := range traitNames {
+		if traitName == name {
+			traits[i] = byte(value)
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown directive .%s", name)
+}
+
+func assembleCommand(name string, args []string) ([]cell.Command, error) {
+	cmd, ok := commandByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown command %s", name)
+	}
+	if len(args) != len(commandArgs[cmd]) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name, len(commandArgs[cmd]), len(args))
+	}
+
+	emit := []cell.Command{cmd}
+	for i, argt := range commandArgs[cmd] {
+		var (
+			value cell.Command
+			err   error
+		)
+		switch argt {
+		case _ARG_CONST:
+			value, err = parseConst(args[i])
+		case _ARG_REG:
+			value, err = parseRegister(args[i])
+		case _ARG_COND:
+			value, err = parseCondition(args[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+		emit = append(emit, value)
+	}
+	return emit, nil
+}
+
+func commandByName(name string) (cell.Command, bool) {
+	for cmd, cmdName := range commandNames {
+		if cmdName == name {
+			return cmd, true
+		}
+	}
+	return 0, false
+}
+
+func parseConst(arg string) (cell.Command, error) {
+	value, err := strconv.ParseUint(arg, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid constant %s", arg)
+	}
+	return cell.Command(value), nil
+}
+
+func parseRegister(arg string) (cell.Command, error) {
+	for reg, regName := range registerNames {
+		if regName == arg {
+			return reg, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid register %s", arg)
+}
+
+func parseCondition(arg string) (cond cell.Command, err error) {
+	for _, part := range strings.Split(arg, "|") {
+		part = strings.TrimSpace(part)
+		found := false
+		// the lowest unknown bit is used for _unk
+		for cind := 0; cind < 256; cind = nextCondition(cind) {
+			if conditionNames[cell.Command(cind)] == part {
+				cond |= cell.Command(cind)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid condition %s", part)
+		}
+	}
+	return
+}
+
+func nextCondition(cind int) int {
+	if cind == 0 {
+		return 1
+	}
+	return cind << 1
+}
+
+func splitArgs(args string) []string {
+	if args == "" {
+		return nil
+	}
+	parts := strings.Split(args, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+func stripComments(code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			lines[i] = line[:idx]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
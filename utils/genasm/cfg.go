@@ -0,0 +1,243 @@
+package genasm
+
+import (
+	"fmt"
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"io"
+	"sort"
+	"strings"
+)
+
+type EdgeKind byte
+
+// Control flow edge kinds list
+const (
+	// Next command, also the not taken branch and the return point of the dive
+	EDGE_NEXT EdgeKind = iota
+	EDGE_JUMP
+	// Taken conditional jump
+	EDGE_BRANCH
+	EDGE_DIVE
+)
+
+type Edge struct {
+	Kind EdgeKind
+	To   uint64
+}
+
+// Commands executed one after another, only the last one may branch
+type Block struct {
+	Start uint64
+	// Addresses of the commands
+	Commands []uint64
+	// Edges to the block starts
+	Edges []Edge
+	// Ends with lift returning to the dive point
+	Lift bool
+	// Ends with the unknown command, the VM stalls on it
+	Stall bool
+}
+
+// Control flow graph of the genome reachable from the entry points
+type CFG struct {
+	Genome  cell.Genome
+	Entries []uint64
+	// Bytes of the reachable commands and arguments
+	Reachable [cell.GenomeLength]bool
+	Blocks    []Block
+}
+
+// Entry points of the cell, the counter start and the sensor jump positions
+func CellEntries(c *cell.Cell) []uint64 {
+	entries := []uint64{0}
+	for _, s := range c.Brain.Sensors {
+		if s.JumpPosition != 0 {
+			entries = append(entries, s.JumpPosition%cell.GenomeLength)
+		}
+	}
+	return entries
+}
+
+// Follow jmp, dive and lift from the entry points, the counter start is always one of them.
+// Jumps may land in the middle of the commands, such bytes are decoded once per landing
+func Analyze(genome cell.Genome, entries ...uint64) *CFG {
+	g := &CFG{Genome: genome, Entries: []uint64{0}}
+	for _, e := range entries {
+		if e%cell.GenomeLength != 0 {
+			g.Entries = append(g.Entries, e%cell.GenomeLength)
+		}
+	}
+
+	var (
+		decoded [cell.GenomeLength]bool
+		edges   [cell.GenomeLength][]Edge
+		preds   [cell.GenomeLength]int
+		leader  [cell.GenomeLength]bool
+	)
+	work := append([]uint64(nil), g.Entries...)
+	for _, e := range g.Entries {
+		leader[e] = true
+	}
+
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+		if decoded[addr] {
+			continue
+		}
+		decoded[addr] = true
+
+		size := commandSize(genome, addr)
+		for i := uint64(0); i < size; i++ {
+			g.Reachable[(addr+i)%cell.GenomeLength] = true
+		}
+
+		edges[addr] = commandEdges(genome, addr, size)
+		for _, e := range edges[addr] {
+			preds[e.To]++
+			if isBranch(genome.Code[addr]) {
+				leader[e.To] = true
+			}
+			work = append(work, e.To)
+		}
+	}
+
+	for addr := range leader {
+		if decoded[addr] && (leader[addr] || preds[addr] > 1) {
+			g.Blocks = append(g.Blocks, g.block(uint64(addr), edges[:], leader[:], preds[:]))
+		}
+	}
+	return g
+}
+
+func (g *CFG) block(start uint64, edges [][]Edge, leader []bool, preds []int) Block {
+	b := Block{Start: start}
+	addr := start
+	for {
+		b.Commands = append(b.Commands, addr)
+		cmd := g.Genome.Code[addr]
+		if isBranch(cmd) {
+			b.Edges = edges[addr]
+			b.Lift = cmd == cell.CMD_LIFT
+			b.Stall = !isKnown(cmd)
+			return b
+		}
+
+		next := edges[addr][0].To
+		if leader[next] || preds[next] > 1 {
+			b.Edges = edges[addr]
+			return b
+		}
+		addr = next
+	}
+}
+
+// Count of the reachable bytes, the genome size that may affect the behaviour
+func (g *CFG) FunctionalSize() int {
+	size := 0
+	for _, r := range g.Reachable {
+		if r {
+			size++
+		}
+	}
+	return size
+}
+
+// Write the graph in the GraphViz DOT language
+func (g *CFG) WriteDOT(writer io.Writer, name string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %q {\n", name)
+	sb.WriteString("  node [shape=box fontname=\"monospace\"];\n")
+
+	blocks := append([]Block(nil), g.Blocks...)
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+	for _, b := range blocks {
+		var label strings.Builder
+		for _, addr := range b.Commands {
+			code, _ := DisassembleCommand(g.Genome, addr)
+			fmt.Fprintf(&label, "%3d: %s\\l", addr, strings.ReplaceAll(code, `"`, `\"`))
+		}
+
+		attrs := ""
+		switch {
+		case b.Stall:
+			attrs = " color=red"
+		case b.Lift:
+			attrs = " peripheries=2"
+		}
+		for _, e := range g.Entries {
+			if e == b.Start {
+				attrs += " style=bold"
+				break
+			}
+		}
+		fmt.Fprintf(&sb, "  b%d [label=\"%s\"%s];\n", b.Start, label.String(), attrs)
+	}
+
+	for _, b := range blocks {
+		for _, e := range b.Edges {
+			fmt.Fprintf(&sb, "  b%d -> b%d%s;\n", b.Start, e.To, edgeStyle[e.Kind])
+		}
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(writer, sb.String())
+	return err
+}
+
+var edgeStyle = map[EdgeKind]string{
+	EDGE_NEXT:   "",
+	EDGE_JUMP:   " [style=bold]",
+	EDGE_BRANCH: " [color=blue label=\"taken\"]",
+	EDGE_DIVE:   " [style=dashed label=\"dive\"]",
+}
+
+// Successors of the command like the VM moves the counter
+func commandEdges(genome cell.Genome, addr, size uint64) []Edge {
+	arg := func(n uint64) uint64 {
+		return uint64(genome.Code[(addr+n)%cell.GenomeLength])
+	}
+	next := Edge{Kind: EDGE_NEXT, To: (addr + size) % cell.GenomeLength}
+
+	switch cmd := genome.Code[addr]; {
+	case cmd == cell.CMD_JMP:
+		if arg(1) == cell.CND_NONE {
+			return []Edge{{Kind: EDGE_JUMP, To: arg(2)}}
+		}
+		return []Edge{{Kind: EDGE_BRANCH, To: arg(2)}, next}
+	case cmd == cell.CMD_DIVE:
+		// not taken or the full stack go to the next command, the lift returns there too
+		return []Edge{{Kind: EDGE_DIVE, To: arg(2)}, next}
+	case cmd == cell.CMD_LIFT:
+		// the return point is the next command of the dive, the empty stack goes on
+		return []Edge{next}
+	case !isKnown(cmd):
+		return nil
+	}
+	return []Edge{next}
+}
+
+func commandSize(genome cell.Genome, addr uint64) uint64 {
+	return uint64(len(commandArgs[genome.Code[addr]])) + 1
+}
+
+func isKnown(cmd cell.Command) bool {
+	_, ok := commandNames[cmd]
+	return ok
+}
+
+func isBranch(cmd cell.Command) bool {
+	return cmd == cell.CMD_JMP || cmd == cell.CMD_DIVE || cmd == cell.CMD_LIFT || !isKnown(cmd)
+}
+
+// Functional size of the cell genomes for the world stats, the sensor handlers are reachable
+type FunctionalSizer struct{}
+
+func (FunctionalSizer) FunctionalSize(obj object.Movable) (hash uint64, size int, ok bool) {
+	c, ok := obj.(*cell.Cell)
+	if !ok {
+		return 0, 0, false
+	}
+	return c.Genome.Hash, Analyze(c.Genome, CellEntries(c)...).FunctionalSize(), true
+}
@@ -0,0 +1,143 @@
+package genasm
+
+import (
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/world"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		file           string
+		functionalSize int
+		blocks         []Block
+	}{
+		{"branch.gasm", 15, []Block{
+			{Start: 0, Commands: []uint64{0, 2}, Edges: []Edge{{EDGE_BRANCH, 10}, {EDGE_NEXT, 5}}},
+			{Start: 5, Commands: []uint64{5, 7}, Edges: []Edge{{EDGE_JUMP, 0}}},
+			{Start: 10, Commands: []uint64{10, 12}, Edges: []Edge{{EDGE_JUMP, 0}}},
+		}},
+		// the nops after the lift run through the genome end back to the start
+		{"dive.gasm", 256, []Block{
+			{Start: 0, Commands: []uint64{0}, Edges: []Edge{{EDGE_DIVE, 6}, {EDGE_NEXT, 3}}},
+			{Start: 3, Commands: []uint64{3}, Edges: []Edge{{EDGE_JUMP, 0}}},
+			{Start: 6, Commands: []uint64{6, 8}, Edges: []Edge{{EDGE_NEXT, 10}}, Lift: true},
+			{Start: 10, Commands: addresses(10, 256), Edges: []Edge{{EDGE_NEXT, 0}}},
+		}},
+		// the argument byte is decoded again as the unknown command the VM stalls on
+		{"jump-inside.gasm", 6, []Block{
+			{Start: 0, Commands: []uint64{0, 3}, Edges: []Edge{{EDGE_JUMP, 2}}},
+			{Start: 2, Commands: []uint64{2}, Stall: true},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			g := Analyze(loadGenome(t, test.file))
+
+			if size := g.FunctionalSize(); size != test.functionalSize {
+				t.Errorf("functional size %d, want %d", size, test.functionalSize)
+			}
+			if !reflect.DeepEqual(g.Blocks, test.blocks) {
+				t.Errorf("blocks\n%+v\nwant\n%+v", g.Blocks, test.blocks)
+			}
+		})
+	}
+}
+
+// Fourteen loops diving to the reproduction check, the nops after its last jump are unreachable
+func TestAnalyzeBase(t *testing.T) {
+	g := Analyze(loadGenome(t, "base.gasm"))
+
+	if size := g.FunctionalSize(); size != 244 {
+		t.Errorf("functional size %d, want 244", size)
+	}
+	if len(g.Blocks) != 17 {
+		t.Fatalf("%d blocks, want 17", len(g.Blocks))
+	}
+	for i, b := range g.Blocks[:14] {
+		want := Block{
+			Start:    uint64(i * 16),
+			Commands: []uint64{uint64(i * 16), uint64(i*16 + 1), uint64(i*16 + 3), uint64(i*16 + 5), uint64(i*16 + 7), uint64(i*16 + 9), uint64(i*16 + 11), uint64(i*16 + 13)},
+			Edges:    []Edge{{EDGE_DIVE, 224}, {EDGE_NEXT, uint64(i*16+16) % 256}},
+		}
+		if !reflect.DeepEqual(b, want) {
+			t.Errorf("block %d\n%+v\nwant\n%+v", i, b, want)
+		}
+	}
+	check := []Block{
+		{Start: 224, Commands: []uint64{224, 226, 229, 232}, Edges: []Edge{{EDGE_NEXT, 234}}, Lift: true},
+		{Start: 234, Commands: []uint64{234, 237, 239}, Edges: []Edge{{EDGE_NEXT, 241}}, Lift: true},
+		{Start: 241, Commands: []uint64{241}, Edges: []Edge{{EDGE_JUMP, 0}}},
+	}
+	if !reflect.DeepEqual(g.Blocks[14:], check) {
+		t.Errorf("reproduction blocks\n%+v\nwant\n%+v", g.Blocks[14:], check)
+	}
+}
+
+// The nops after the reproduction check are reachable only from the sensor handler
+func TestFunctionalSizer(t *testing.T) {
+	tests := []struct {
+		name    string
+		sensors []uint64
+		size    int
+	}{
+		{"no sensors", nil, 244},
+		{"handler in the dead code", []uint64{245}, 255},
+		{"handler in the live code", []uint64{16}, 244},
+		{"two handlers", []uint64{250, 245}, 255},
+	}
+
+	genome := loadGenome(t, "base.gasm")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := cell.New(world.New(4, 4, time.Millisecond), nil, object.Position{X: 1, Y: 1})
+			c.Genome = genome
+			for i, addr := range test.sensors {
+				c.Brain.Sensors[i].JumpPosition = addr
+			}
+
+			hash, size, ok := FunctionalSizer{}.FunctionalSize(c)
+			if !ok || hash != genome.Hash {
+				t.Fatalf("hash %d, ok %v", hash, ok)
+			}
+			if size != test.size {
+				t.Errorf("functional size %d, want %d", size, test.size)
+			}
+		})
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := Analyze(loadGenome(t, "branch.gasm"))
+	var sb strings.Builder
+	if err := g.WriteDOT(&sb, "branch"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`digraph "branch" {`,
+		`  b0 [label="  0: nrg   r1\l  2: jmp   _less, 10\l" style=bold];`,
+		`  b0 -> b10 [color=blue label="taken"];`,
+		`  b0 -> b5;`,
+		`  b5 -> b0 [style=bold];`,
+		`  b10 -> b0 [style=bold];`,
+	}
+	for _, line := range want {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("missing %q in\n%s", line, sb.String())
+		}
+	}
+}
+
+func addresses(from, to uint64) []uint64 {
+	var list []uint64
+	for addr := from; addr < to; addr++ {
+		list = append(list, addr)
+	}
+	return list
+}
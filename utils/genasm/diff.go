@@ -0,0 +1,174 @@
+package genasm
+
+import (
+	"fmt"
+	"gopher-dish/cell"
+	"strings"
+)
+
+const (
+	// Unchanged lines around the changes of the unified diff
+	DiffContext = 3
+)
+
+type DiffKind byte
+
+// Diff line kinds list
+const (
+	DIFF_EQUAL DiffKind = iota
+	DIFF_REMOVED
+	DIFF_ADDED
+)
+
+// Trait or command of the listing, traits have no address
+type Instruction struct {
+	Addr  uint64
+	Trait bool
+	Code  string
+}
+
+// Line of the diff, the line numbers are the indexes in the listings
+type DiffLine struct {
+	Kind         DiffKind
+	LineA, LineB int
+	A, B         Instruction
+}
+
+// Commands and traits of the genome in the order of the listing
+func Instructions(genome cell.Genome) []Instruction {
+	var list []Instruction
+	for i, value := range genome.Traits {
+		list = append(list, Instruction{Trait: true, Code: fmt.Sprintf(".%s %d", traitNames[i], value)})
+	}
+	for addr := uint64(0); addr < cell.GenomeLength; {
+		code, size := DisassembleCommand(genome, addr)
+		list = append(list, Instruction{Addr: addr, Code: code})
+		addr += size
+	}
+	return list
+}
+
+// Align the listings of the genomes by the longest common subsequence of the instructions,
+// so a changed byte doesn't break the alignment of the commands decoded after it
+func Diff(a, b cell.Genome) []DiffLine {
+	la, lb := Instructions(a), Instructions(b)
+
+	// lcs[i][j] is the common length of la[i:] and lb[j:]
+	lcs := make([][]int, len(la)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(lb)+1)
+	}
+	for i := len(la) - 1; i >= 0; i-- {
+		for j := len(lb) - 1; j >= 0; j-- {
+			if la[i].Code == lb[j].Code {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && j < len(lb) && la[i].Code == lb[j].Code:
+			lines = append(lines, DiffLine{Kind: DIFF_EQUAL, LineA: i, LineB: j, A: la[i], B: lb[j]})
+			i++
+			j++
+		case j == len(lb) || i < len(la) && lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Kind: DIFF_REMOVED, LineA: i, LineB: j, A: la[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Kind: DIFF_ADDED, LineA: i, LineB: j, B: lb[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// Count of the changed lines
+func DiffChanges(lines []DiffLine) (changes int) {
+	for _, l := range lines {
+		if l.Kind != DIFF_EQUAL {
+			changes++
+		}
+	}
+	return
+}
+
+// Unified diff of the listings with the addresses in the comments,
+// it is empty for the same listings
+func UnifiedDiff(lines []DiffLine, nameA, nameB string, context int) string {
+	var sb strings.Builder
+	for start := 0; start < len(lines); {
+		// next hunk from the change with the context around it
+		first := start
+		for first < len(lines) && lines[first].Kind == DIFF_EQUAL {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		begin := first - context
+		if begin < start {
+			begin = start
+		}
+
+		end, equal := first, 0
+		for end < len(lines) && equal <= 2*context {
+			if lines[end].Kind == DIFF_EQUAL {
+				equal++
+			} else {
+				equal = 0
+			}
+			end++
+		}
+		end -= equal
+		if equal > context {
+			end += context
+		} else {
+			end += equal
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		writeHunk(&sb, lines[begin:end])
+		start = end
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, hunk []DiffLine) {
+	var countA, countB int
+	for _, l := range hunk {
+		if l.Kind != DIFF_ADDED {
+			countA++
+		}
+		if l.Kind != DIFF_REMOVED {
+			countB++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", hunk[0].LineA+1, countA, hunk[0].LineB+1, countB)
+
+	for _, l := range hunk {
+		switch l.Kind {
+		case DIFF_EQUAL:
+			fmt.Fprintf(sb, " %s\n", diffCode(l.B, fmt.Sprintf("%3d %3d", l.A.Addr, l.B.Addr)))
+		case DIFF_REMOVED:
+			fmt.Fprintf(sb, "-%s\n", diffCode(l.A, fmt.Sprintf("%3d", l.A.Addr)))
+		case DIFF_ADDED:
+			fmt.Fprintf(sb, "+%s\n", diffCode(l.B, fmt.Sprintf("    %3d", l.B.Addr)))
+		}
+	}
+}
+
+func diffCode(in Instruction, addr string) string {
+	if in.Trait {
+		return in.Code + ";"
+	}
+	return fmt.Sprintf("%-28s # %s", in.Code+";", addr)
+}
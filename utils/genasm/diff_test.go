@@ -0,0 +1,103 @@
+package genasm
+
+import (
+	"gopher-dish/cell"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		file    string
+		changes int
+		diff    string
+	}{
+		{"base.gasm", 0, ""},
+		{"base-arg.gasm", 2, `--- a
++++ b
+@@ -117,7 +117,7 @@
+ recl  1;                     # 219 219
+ dive  _none, 224;            # 221 221
+ nrg   r1;                    # 224 224
+-put   r2, 96;                # 226
++put   r2, 97;                #     226
+ cmp   r1, r2;                # 229 229
+ lift  _eq | _less;           # 232 232
+ put   r2, 19;                # 234 234
+`},
+		// the commands after the insertion stay aligned, the shifted out nops are removed
+		{"base-insert.gasm", 3, `--- a
++++ b
+@@ -11,6 +11,7 @@
+ recl  1;                     #   7   7
+ recl  1;                     #   9   9
+ recl  1;                     #  11  11
++recl  1;                     #      13
+ dive  _none, 224;            #  13  15
+ nop;                         #  16  18
+ recl  1;                     #  17  19
+@@ -134,5 +135,3 @@
+ nop;                         # 251 253
+ nop;                         # 252 254
+ nop;                         # 253 255
+-nop;                         # 254
+-nop;                         # 255
+`},
+		{"base-trait.gasm", 2, `--- a
++++ b
+@@ -1,6 +1,6 @@
+ .weight 5;
+ .armour 0;
+-.bite 40;
++.bite 41;
+ .photosynthesis 16;
+ .maxenergy 255;
+ .sight 8;
+`},
+	}
+
+	base := loadGenome(t, "base.gasm")
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			lines := Diff(base, loadGenome(t, test.file))
+
+			if changes := DiffChanges(lines); changes != test.changes {
+				t.Errorf("%d lines changed, want %d", changes, test.changes)
+			}
+			if diff := UnifiedDiff(lines, "a", "b", DiffContext); diff != test.diff {
+				t.Errorf("diff:\n%s\nwant:\n%s", diff, test.diff)
+			}
+
+			// every instruction of both listings is in the diff once and in order
+			nextA, nextB := 0, 0
+			for _, l := range lines {
+				if l.Kind != DIFF_ADDED {
+					if l.LineA != nextA {
+						t.Fatalf("line %d of a, want %d", l.LineA, nextA)
+					}
+					nextA++
+				}
+				if l.Kind != DIFF_REMOVED {
+					if l.LineB != nextB {
+						t.Fatalf("line %d of b, want %d", l.LineB, nextB)
+					}
+					nextB++
+				}
+			}
+		})
+	}
+}
+
+func loadGenome(t *testing.T, name string) cell.Genome {
+	t.Helper()
+	code, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	genome, err := Assemble(string(code))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return genome
+}
@@ -3,54 +3,87 @@ package genasm
 import (
 	"fmt"
 	"gopher-dish/cell"
-	"gopher-dish/utils"
 )
 
 func Disassemble(genome cell.Genome) (code string) {
-	var cmditr utils.Iterator
-
-	for cmditr < cell.GenomeLength {
-		cmd := genome.Code[cmditr.Inc()]
-		cmdName, ok := commandNames[cmd]
-		if !ok || cmd == cell.CMD_NOP {
-			code += fmt.Sprintf("%s;\n", commandNames[cell.CMD_NOP])
-			continue
-		}
-		if len(commandArgs[cmd]) == 0 {
-			code += fmt.Sprintf("%s;\n", cmdName)
-			continue
-		}
-		code += fmt.Sprintf("%-5s ", cmdName)
-		for i, argt := range commandArgs[cmd] {
-			switch argt {
-			case _ARG_CONST:
-				code += fmt.Sprint(genome.Code[cmditr.Inc()])
-			case _ARG_REG:
-				code += registerNames[genome.Code[cmditr.Inc()]%cell.RegistersCount]
-			case _ARG_COND:
-				cond := genome.Code[cmditr.Inc()]
-				condCnt := 0
-				for cind := 1; cind < 256; cind <<= 1 {
-					if (int(cond) & cind) > 0 {
-						code += conditionNames[cell.Command(cind)]
-						code += " | "
-						condCnt++
-					}
-				}
-				if condCnt > 0 {
-					code = code[:len(code)-3]
-				} else {
-					code += conditionNames[0]
+	code = DisassembleTraits(genome.Traits)
+
+	for addr := uint64(0); addr < cell.GenomeLength; {
+		line, size := DisassembleCommand(genome, addr)
+		code += line + ";\n"
+		addr += size
+	}
+
+	code += "\n"
+	return
+}
+
+// Listing with the address of every command in the comment, it is still assembled back
+func DisassembleAddressed(genome cell.Genome) (code string) {
+	code = DisassembleTraits(genome.Traits)
+
+	for addr := uint64(0); addr < cell.GenomeLength; {
+		line, size := DisassembleCommand(genome, addr)
+		code += fmt.Sprintf("%-28s # %3d\n", line+";", addr)
+		addr += size
+	}
+
+	code += "\n"
+	return
+}
+
+// Disassemble one command at the address, arguments wrap around the genome end like in the VM
+func DisassembleCommand(genome cell.Genome, addr uint64) (code string, size uint64) {
+	addr %= cell.GenomeLength
+	cmd := genome.Code[addr]
+	size = 1
+
+	cmdName, ok := commandNames[cmd]
+	if !ok {
+		// the VM stalls on unknown commands, keep them apart from nop
+		return fmt.Sprintf(".byte %d", cmd), size
+	}
+	if len(commandArgs[cmd]) == 0 {
+		return cmdName, size
+	}
+
+	code = fmt.Sprintf("%-5s ", cmdName)
+	for i, argt := range commandArgs[cmd] {
+		arg := genome.Code[(addr+size)%cell.GenomeLength]
+		size++
+
+		switch argt {
+		case _ARG_CONST:
+			code += fmt.Sprint(arg)
+		case _ARG_REG:
+			code += registerNames[arg%cell.RegistersCount]
+		case _ARG_COND:
+			condCnt := 0
+			for cind := 1; cind < 256; cind <<= 1 {
+				if (int(arg) & cind) > 0 {
+					code += conditionNames[cell.Command(cind)]
+					code += " | "
+					condCnt++
 				}
 			}
-
-			if i < len(commandArgs[cmd])-1 {
-				code += ", "
+			if condCnt > 0 {
+				code = code[:len(code)-3]
+			} else {
+				code += conditionNames[0]
 			}
 		}
-		code += ";\n"
+
+		if i < len(commandArgs[cmd])-1 {
+			code += ", "
+		}
 	}
+	return
+}
 
+func DisassembleTraits(traits cell.Traits) (code string) {
+	for i, value := range traits {
+		code += fmt.Sprintf(".%-15s %d;\n", traitNames[i], value)
+	}
 	code += "\n"
 	return
 }
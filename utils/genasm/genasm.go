@@ -1,6 +1,9 @@
 package genasm
 
-import "gopher-dish/cell"
+import (
+	"fmt"
+	"gopher-dish/cell"
+)
 
 type argType byte
 
@@ -19,6 +22,7 @@ var commandNames = map[cell.Command]string{
 	cell.CMD_LIFT: "lift",
 	// Memory commands
 	cell.CMD_PUT:  "put",
+	cell.CMD_RAND: "rand",
 	cell.CMD_SAVE: "save",
 	cell.CMD_LOAD: "load",
 	// Math commands
@@ -47,6 +51,19 @@ var commandNames = map[cell.Command]string{
 	cell.CMD_GETHEALTH:  "heal",
 	cell.CMD_GETENERGY:  "nrg",
 	cell.CMD_GETCOUNTER: "cntr",
+	// Colony commands
+	cell.CMD_BOND:      "bond",
+	cell.CMD_UNBOND:    "ubnd",
+	cell.CMD_CHECKBOND: "cbnd",
+	cell.CMD_BONDMASK:  "bmsk",
+	// Signal commands
+	cell.CMD_EMIT:     "emit",
+	cell.CMD_SMELL:    "smel",
+	cell.CMD_SMELLDIR: "sdir",
+	// Vision commands
+	cell.CMD_LOOK: "look",
+	// Body commands
+	cell.CMD_GETTRAIT: "trait",
 }
 
 var commandArgs = map[cell.Command][]argType{
@@ -58,6 +75,7 @@ var commandArgs = map[cell.Command][]argType{
 	cell.CMD_LIFT: {_ARG_COND},
 	// Memory commands
 	cell.CMD_PUT:  {_ARG_REG, _ARG_CONST},
+	cell.CMD_RAND: {_ARG_REG},
 	cell.CMD_SAVE: {_ARG_REG, _ARG_CONST},
 	cell.CMD_LOAD: {_ARG_REG, _ARG_CONST},
 	// Math commands
@@ -86,6 +104,28 @@ var commandArgs = map[cell.Command][]argType{
 	cell.CMD_GETHEALTH:  {_ARG_REG},
 	cell.CMD_GETENERGY:  {_ARG_REG},
 	cell.CMD_GETCOUNTER: {_ARG_REG},
+	// Colony commands
+	cell.CMD_BOND:      {_ARG_REG},
+	cell.CMD_UNBOND:    {_ARG_REG},
+	cell.CMD_CHECKBOND: {_ARG_REG, _ARG_REG},
+	cell.CMD_BONDMASK:  {_ARG_REG},
+	// Signal commands
+	cell.CMD_EMIT:     {_ARG_REG, _ARG_REG},
+	cell.CMD_SMELL:    {_ARG_REG, _ARG_REG},
+	cell.CMD_SMELLDIR: {_ARG_REG, _ARG_REG},
+	// Vision commands
+	cell.CMD_LOOK: {_ARG_REG, _ARG_REG, _ARG_REG},
+	// Body commands
+	cell.CMD_GETTRAIT: {_ARG_REG, _ARG_CONST},
+}
+
+var traitNames = [cell.TRAIT_ENUM_SIZE]string{
+	cell.TRAIT_WEIGHT:         "weight",
+	cell.TRAIT_ARMOUR:         "armour",
+	cell.TRAIT_BITE:           "bite",
+	cell.TRAIT_PHOTOSYNTHESIS: "photosynthesis",
+	cell.TRAIT_MAXENERGY:      "maxenergy",
+	cell.TRAIT_SIGHT:          "sight",
 }
 
 var registerNames = map[cell.Command]string{
@@ -109,3 +149,16 @@ var conditionNames = map[cell.Command]string{
 
 	cell.Command(128): "_unk",
 }
+
+// Mnemonics of all commands indexed by the opcode, unnamed ones are numbered
+func CommandNames() []string {
+	names := make([]string, cell.CMD_ENUM_SIZE)
+	for i := range names {
+		if name, ok := commandNames[cell.Command(i)]; ok {
+			names[i] = name
+		} else {
+			names[i] = fmt.Sprintf("cmd%d", i)
+		}
+	}
+	return names
+}
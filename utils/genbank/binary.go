@@ -0,0 +1,97 @@
+package genbank
+
+import (
+	"encoding/binary"
+	"fmt"
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"io"
+)
+
+const (
+	// "GDG\0" in little endian
+	binaryMagic   = 0x00474447
+	binaryVersion = 1
+)
+
+type bHeader struct {
+	Magic   uint32
+	Version uint32
+	Count   uint32
+}
+
+type bEntryDescriptor struct {
+	Tick         uint64
+	Generation   uint64
+	Ancestry     object.ParentsChain
+	Population   uint64
+	OriginLength uint32
+}
+
+func WriteBinary(writer io.Writer, entries []Entry) error {
+	err := binary.Write(writer, binary.LittleEndian, bHeader{Magic: binaryMagic, Version: binaryVersion, Count: uint32(len(entries))})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		desc := bEntryDescriptor{
+			Tick:         e.Tick,
+			Generation:   e.Generation,
+			Ancestry:     e.Ancestry,
+			Population:   e.Population,
+			OriginLength: uint32(len(e.Origin)),
+		}
+		if err = binary.Write(writer, binary.LittleEndian, desc); err != nil {
+			return err
+		}
+		if _, err = io.WriteString(writer, e.Origin); err != nil {
+			return err
+		}
+		if err = binary.Write(writer, binary.LittleEndian, e.Genome); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readBinary(reader io.Reader) (entries []Entry, err error) {
+	var header bHeader
+	if err = binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return
+	}
+	if header.Magic != binaryMagic {
+		return nil, fmt.Errorf("not a genome file")
+	}
+	if header.Version > binaryVersion {
+		return nil, fmt.Errorf("unsupported genome file version %d", header.Version)
+	}
+
+	for i := 0; i < int(header.Count); i++ {
+		var desc bEntryDescriptor
+		if err = binary.Read(reader, binary.LittleEndian, &desc); err != nil {
+			return
+		}
+		origin := make([]byte, desc.OriginLength)
+		if _, err = io.ReadFull(reader, origin); err != nil {
+			return
+		}
+
+		var genome cell.Genome
+		if err = binary.Read(reader, binary.LittleEndian, &genome); err != nil {
+			return
+		}
+
+		entries = append(entries, Entry{
+			Metadata: Metadata{
+				Origin:     string(origin),
+				Tick:       desc.Tick,
+				Generation: desc.Generation,
+				Ancestry:   desc.Ancestry,
+				Population: desc.Population,
+			},
+			Genome: genome,
+		})
+	}
+	return
+}
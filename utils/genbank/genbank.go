@@ -0,0 +1,173 @@
+package genbank
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/world"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Format byte
+
+// Genome file formats list
+const (
+	FORMAT_TEXT Format = iota
+	FORMAT_BINARY
+)
+
+const (
+	BinaryExtension = ".gdg"
+	TextExtension   = ".gasm"
+)
+
+type Metadata struct {
+	// World file the genome was taken from
+	Origin     string
+	Tick       uint64
+	Generation uint64
+	Ancestry   object.ParentsChain
+	// Cells with the genome at the moment of the export
+	Population uint64
+}
+
+type Entry struct {
+	Metadata
+	Genome cell.Genome
+}
+
+func FormatByPath(path string) Format {
+	if filepath.Ext(path) == BinaryExtension {
+		return FORMAT_BINARY
+	}
+	return FORMAT_TEXT
+}
+
+// The most common genomes of the living cells, the oldest cell of every
+// genome describes its generation and ancestry. Genomes are told apart by
+// the whole value, the hash is too weak for this
+func Top(w *world.World, k int, origin string) []Entry {
+	type group struct {
+		population uint64
+		oldest     *cell.Cell
+	}
+	groups := make(map[cell.Genome]*group)
+
+	for _, obj := range w.Objects {
+		c, ok := obj.(*cell.Cell)
+		if !ok || c.Died {
+			continue
+		}
+		g, ok := groups[c.Genome]
+		if !ok {
+			g = &group{oldest: c}
+			groups[c.Genome] = g
+		}
+		g.population++
+		if c.Name < g.oldest.Name {
+			g.oldest = c
+		}
+	}
+
+	sorted := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.population != b.population {
+			return a.population > b.population
+		}
+		if a.oldest.Genome.Hash != b.oldest.Genome.Hash {
+			return a.oldest.Genome.Hash < b.oldest.Genome.Hash
+		}
+		return a.oldest.Name < b.oldest.Name
+	})
+	if k > 0 && len(sorted) > k {
+		sorted = sorted[:k]
+	}
+
+	entries := make([]Entry, 0, len(sorted))
+	for _, g := range sorted {
+		entries = append(entries, Entry{
+			Metadata: Metadata{
+				Origin:     origin,
+				Tick:       w.Ticks,
+				Generation: g.oldest.Generation,
+				Ancestry:   g.oldest.ParentsChain,
+				Population: g.population,
+			},
+			Genome: g.oldest.Genome,
+		})
+	}
+	return entries
+}
+
+// Create the cell with the stored genome, safe for the running world.
+// X wraps around the world as the cells move, Y must be inside it.
+// Ancestry refers to the origin world and is not inherited
+func Inject(w *world.World, e Entry, pos object.Position) (*cell.Cell, error) {
+	if pos.Y < 0 || pos.Y >= int32(w.Height) {
+		return nil, fmt.Errorf("position [%d, %d] is out of the world", pos.X, pos.Y)
+	}
+	width := int32(w.Width)
+	pos.X = (pos.X%width + width) % width
+
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+
+	c := cell.New(w, nil, pos)
+	if c == nil {
+		return nil, fmt.Errorf("position [%d, %d] is taken", pos.X, pos.Y)
+	}
+	c.Genome = e.Genome
+	c.Weight = e.Genome.Traits[cell.TRAIT_WEIGHT]
+	c.Generation = e.Generation
+	return c, nil
+}
+
+func Write(writer io.Writer, entries []Entry, format Format) error {
+	if format == FORMAT_BINARY {
+		return WriteBinary(writer, entries)
+	}
+	return WriteText(writer, entries)
+}
+
+// Read the genome file of any format
+func Read(reader io.Reader) ([]Entry, error) {
+	buffered := bufio.NewReader(reader)
+	magic, err := buffered.Peek(4)
+	if err == nil && binary.LittleEndian.Uint32(magic) == binaryMagic {
+		return readBinary(buffered)
+	}
+	return readText(buffered)
+}
+
+// Positions in the "x:y,x:y" form inside the width x height world
+func ParsePositions(arg string, width, height int32) (positions []object.Position, err error) {
+	for _, pair := range strings.Split(arg, ",") {
+		xs, ys, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid position %s", pair)
+		}
+		x, err := strconv.ParseInt(xs, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.ParseInt(ys, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		if x < 0 || x >= int64(width) || y < 0 || y >= int64(height) {
+			return nil, fmt.Errorf("position %s is out of the %dx%d world", pair, width, height)
+		}
+		positions = append(positions, object.Position{X: int32(x), Y: int32(y)})
+	}
+	return
+}
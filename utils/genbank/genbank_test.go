@@ -0,0 +1,103 @@
+package genbank
+
+import (
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/world"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInject(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  object.Position
+		// position the cell is placed at, the error otherwise
+		placed object.Position
+		err    string
+	}{
+		{"inside", object.Position{X: 4, Y: 3}, object.Position{X: 4, Y: 3}, ""},
+		{"right of the world", object.Position{X: 15, Y: 3}, object.Position{X: 5, Y: 3}, ""},
+		{"left of the world", object.Position{X: -3, Y: 3}, object.Position{X: 7, Y: 3}, ""},
+		{"below the world", object.Position{X: 4, Y: 10}, object.Position{}, "out of the world"},
+		{"above the world", object.Position{X: 4, Y: -1}, object.Position{}, "out of the world"},
+		{"taken", object.Position{X: 11, Y: 8}, object.Position{}, "is taken"},
+	}
+
+	// every command moves the cell ahead
+	var code [cell.GenomeLength]cell.Command
+	for i := range code {
+		code[i] = cell.CMD_MOVE
+	}
+	entry := Entry{Genome: cell.NewGenome(code, cell.BaseTraits())}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := world.New(10, 10, time.Millisecond)
+			cell.New(w, nil, object.Position{X: 1, Y: 8})
+
+			c, err := Inject(w, entry, test.pos)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Position != test.placed || w.Places[test.placed.X][test.placed.Y] != c {
+				t.Fatalf("cell at [%d, %d], want [%d, %d]", c.Position.X, c.Position.Y, test.placed.X, test.placed.Y)
+			}
+
+			for i := 0; i < 5; i++ {
+				w.Handle()
+			}
+			for id, obj := range w.Objects {
+				p := obj.GetPosition()
+				if p.X < 0 || p.X >= int32(w.Width) || w.Places[p.X][p.Y] != obj {
+					t.Errorf("object %d is not on its square [%d, %d]", id, p.X, p.Y)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	tests := []struct {
+		arg       string
+		positions []object.Position
+		err       string
+	}{
+		{"0:0,9:9", []object.Position{{X: 0, Y: 0}, {X: 9, Y: 9}}, ""},
+		{"3:4", []object.Position{{X: 3, Y: 4}}, ""},
+		{"10:4", nil, "out of the 10x10 world"},
+		{"3:-1", nil, "out of the 10x10 world"},
+		{"3", nil, "invalid position"},
+		{"a:4", nil, "invalid syntax"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.arg, func(t *testing.T) {
+			positions, err := ParsePositions(test.arg, 10, 10)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(positions) != len(test.positions) {
+				t.Fatalf("positions %v, want %v", positions, test.positions)
+			}
+			for i := range positions {
+				if positions[i] != test.positions[i] {
+					t.Errorf("positions %v, want %v", positions, test.positions)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package genbank
+
+import (
+	"encoding/hex"
+	"fmt"
+	"gopher-dish/cell"
+	"gopher-dish/utils/genasm"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Line between the genomes of the text file
+const textSeparator = "---"
+
+// Text form is the genasm listing with the metadata in the comments.
+// The listing shows arguments in the canonical form, so the raw code is kept
+// in the comments as well and restores the exact genome unless the listing is edited
+func WriteText(writer io.Writer, entries []Entry) error {
+	for i, e := range entries {
+		if i > 0 {
+			if _, err := fmt.Fprintln(writer, textSeparator); err != nil {
+				return err
+			}
+		}
+
+		ancestry := make([]string, len(e.Ancestry))
+		for j, id := range e.Ancestry {
+			ancestry[j] = strconv.FormatUint(id, 10)
+		}
+
+		code := make([]byte, len(e.Genome.Code))
+		for j, cmd := range e.Genome.Code {
+			code[j] = byte(cmd)
+		}
+
+		_, err := fmt.Fprintf(writer, "# origin: %s\n# tick: %d\n# generation: %d\n# ancestry: %s\n# population: %d\n# hash: %d\n# code: %x\n%s",
+			e.Origin, e.Tick, e.Generation, strings.Join(ancestry, " "), e.Population, e.Genome.Hash, code, genasm.Disassemble(e.Genome))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readText(reader io.Reader) (entries []Entry, err error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	for i, chunk := range splitText(string(data)) {
+		var e Entry
+		if e.Genome, err = genasm.Assemble(chunk); err != nil {
+			return nil, fmt.Errorf("genome %d: %w", i+1, err)
+		}
+		code, err := parseMetadata(chunk, &e.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("genome %d: %w", i+1, err)
+		}
+		if raw, ok := restoreCode(code, e.Genome); ok {
+			e.Genome = raw
+		}
+		entries = append(entries, e)
+	}
+	return
+}
+
+func splitText(data string) (chunks []string) {
+	var chunk []string
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == textSeparator {
+			chunks = append(chunks, strings.Join(chunk, "\n"))
+			chunk = nil
+			continue
+		}
+		chunk = append(chunk, line)
+	}
+	if strings.TrimSpace(strings.Join(chunk, "")) != "" {
+		chunks = append(chunks, strings.Join(chunk, "\n"))
+	}
+	return
+}
+
+// Genome with the raw code if it has the same listing as the assembled one
+func restoreCode(code []byte, assembled cell.Genome) (cell.Genome, bool) {
+	if len(code) != cell.GenomeLength {
+		return assembled, false
+	}
+
+	var raw [cell.GenomeLength]cell.Command
+	for i, b := range code {
+		raw[i] = cell.Command(b)
+	}
+	genome := cell.NewGenome(raw, assembled.Traits)
+
+	canonical, err := genasm.Assemble(genasm.Disassemble(genome))
+	if err != nil || canonical.Code != assembled.Code {
+		return assembled, false
+	}
+	return genome, true
+}
+
+func parseMetadata(chunk string, meta *Metadata) (code []byte, err error) {
+	for _, line := range strings.Split(chunk, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line[1:]), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "origin":
+			meta.Origin = value
+		case "tick":
+			meta.Tick, err = strconv.ParseUint(value, 10, 64)
+		case "generation":
+			meta.Generation, err = strconv.ParseUint(value, 10, 64)
+		case "population":
+			meta.Population, err = strconv.ParseUint(value, 10, 64)
+		case "code":
+			code, err = hex.DecodeString(value)
+		case "ancestry":
+			for i, field := range strings.Fields(value) {
+				if i >= len(meta.Ancestry) {
+					break
+				}
+				if meta.Ancestry[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", key, value)
+		}
+	}
+	return
+}
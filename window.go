@@ -0,0 +1,14 @@
+//go:build !nogui
+
+package main
+
+import (
+	"gopher-dish/gui"
+	"gopher-dish/world"
+)
+
+const windowAvailable = true
+
+func runWindow(w *world.World) {
+	gui.Run(UITickInterval, w)
+}
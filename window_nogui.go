@@ -0,0 +1,10 @@
+//go:build nogui
+
+package main
+
+import "gopher-dish/world"
+
+// Built without OpenGL for the headless servers
+const windowAvailable = false
+
+func runWindow(w *world.World) {}
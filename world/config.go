@@ -0,0 +1,58 @@
+package world
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	WorldStatsInterval = 100
+	WorldLookRange     = 16
+	WorldMaxEnergy     = 255
+	WorldMaxHealth     = 255
+)
+
+type Config struct {
+	// Period of the world summary records in the stats stream, in ticks
+	StatsInterval uint64
+	// Scripted and random world events
+	Events []EventConfig
+	// Diffusion and evaporation of the signal channels
+	Signals [SignalChannels]SignalConfig
+	// Maximum distance seen by the LOOK command
+	LookRange uint8
+	// Energy and health caps, the VM sees them scaled to a byte.
+	// The defaults keep the scale 1:1 for the base genome thresholds
+	MaxEnergy uint32
+	MaxHealth uint32
+}
+
+func DefaultConfig() Config {
+	cfg := Config{
+		StatsInterval: WorldStatsInterval,
+		LookRange:     WorldLookRange,
+		MaxEnergy:     WorldMaxEnergy,
+		MaxHealth:     WorldMaxHealth,
+	}
+
+	for i := range cfg.Signals {
+		cfg.Signals[i] = SignalConfig{
+			Diffusion:   WorldSignalDiffusion,
+			Evaporation: WorldSignalEvaporation,
+		}
+	}
+
+	return cfg
+}
+
+func LoadConfig(reader io.Reader) (cfg Config, err error) {
+	cfg = DefaultConfig()
+	err = cfg.Apply(reader)
+	return
+}
+
+// Override only the values present in the JSON, so the caps restored
+// from a save survive a config without them
+func (cfg *Config) Apply(reader io.Reader) error {
+	return json.NewDecoder(reader).Decode(cfg)
+}
@@ -0,0 +1,357 @@
+package world
+
+import (
+	"gopher-dish/object"
+)
+
+const (
+	// Edits kept for undo, the history is cleared by the next tick
+	// and by any change of the objects made outside of the edits
+	WorldEditHistory = 32
+)
+
+type objectState struct {
+	obj      object.Movable
+	restore  func()
+	position object.Position
+	placed   bool
+}
+
+type worldEdit struct {
+	objects       map[uint64]bool
+	before, after []objectState
+
+	paintBefore, paintAfter map[string][]float32
+}
+
+// Group the following edits into one undo step until EndEdit
+func (w *World) BeginEdit() {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	w.beginEdit()
+}
+
+func (w *World) EndEdit() {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	w.endEdit()
+}
+
+func (w *World) Undo() bool {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+
+	if !w.canEdit() || w.currentEdit != nil || w.editIndex == 0 {
+		return false
+	}
+	e := w.edits[w.editIndex-1]
+	if !w.canApplyObjectStates(e.before) {
+		return false
+	}
+	w.editIndex--
+	w.applyObjectStates(e.before)
+	w.applyPaint(e.paintBefore)
+	return true
+}
+
+func (w *World) Redo() bool {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+
+	if !w.canEdit() || w.currentEdit != nil || w.editIndex == len(w.edits) {
+		return false
+	}
+	e := w.edits[w.editIndex]
+	if !w.canApplyObjectStates(e.after) {
+		return false
+	}
+	w.editIndex++
+	w.applyObjectStates(e.after)
+	w.applyPaint(e.paintAfter)
+	return true
+}
+
+// Kill all living objects in the radius, returns the number of victims
+func (w *World) KillInRadius(center object.Position, radius int32) (killed int) {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	if !w.canEdit() {
+		return
+	}
+
+	single := w.beginEdit()
+	w.forEachInRadius(center, radius, func(obj object.Movable) {
+		lively, ok := obj.(object.Lively)
+		if !ok || lively.IsDied() {
+			return
+		}
+		w.touchObject(obj)
+		lively.Die(object.DEATH_EDIT)
+		killed++
+	})
+	if single {
+		w.endEdit()
+	}
+	return
+}
+
+// Remove all objects in the rectangle, corners are included
+func (w *World) EraseRect(from, to object.Position) (erased int) {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	if !w.canEdit() {
+		return
+	}
+
+	if from.X > to.X {
+		from.X, to.X = to.X, from.X
+	}
+	if from.Y > to.Y {
+		from.Y, to.Y = to.Y, from.Y
+	}
+
+	single := w.beginEdit()
+	for x := from.X; x <= to.X; x++ {
+		for y := from.Y; y <= to.Y; y++ {
+			if y < 0 || y >= int32(w.Height) {
+				continue
+			}
+			obj := w.Places[(x%int32(w.Width)+int32(w.Width))%int32(w.Width)][y]
+			if obj == nil {
+				continue
+			}
+			w.touchObject(obj)
+			w.takeObject(obj)
+			erased++
+		}
+	}
+	if single {
+		w.endEdit()
+	}
+	return
+}
+
+// Move the object to the free square, bonds to the cells left behind break
+func (w *World) DragObject(id uint64, pos object.Position) bool {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	if !w.canEdit() {
+		return false
+	}
+
+	obj, ok := w.Objects[id].(object.Editable)
+	pos.X = (pos.X + int32(w.Width)) % int32(w.Width)
+	if !ok || !w.IsPlaceFree(pos) {
+		return false
+	}
+
+	single := w.beginEdit()
+	w.touchObject(obj)
+	current := obj.GetPosition()
+	w.Places[current.X][current.Y] = nil
+	w.Places[pos.X][pos.Y] = obj
+	obj.SetPosition(pos)
+	if single {
+		w.endEdit()
+	}
+	return true
+}
+
+func (w *World) SetObjectEnergy(id uint64, energy uint32) bool {
+	return w.editObject(id, func(obj object.Editable) {
+		obj.SetEnergy(energy)
+	})
+}
+
+func (w *World) SetObjectHealth(id uint64, health uint32) bool {
+	return w.editObject(id, func(obj object.Editable) {
+		obj.SetHealth(health)
+	})
+}
+
+// Add the value to the field in the radius on top of its calculated map
+func (w *World) PaintField(name string, center object.Position, radius int32, value float64) bool {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	if !w.canEdit() {
+		return false
+	}
+	if _, exists := w.Fields[name]; !exists {
+		return false
+	}
+
+	single := w.beginEdit()
+	paint := w.touchPaint(name)
+	for x := center.X - radius; x <= center.X+radius; x++ {
+		for y := center.Y - radius; y <= center.Y+radius; y++ {
+			pos := object.Position{X: (x%int32(w.Width) + int32(w.Width)) % int32(w.Width), Y: y}
+			if y < 0 || y >= int32(w.Height) || w.distanceSq(pos, center) > radius*radius {
+				continue
+			}
+			paint[int(pos.X)*int(w.Height)+int(pos.Y)] += float32(value)
+		}
+	}
+	w.calculateField(name)
+	if single {
+		w.endEdit()
+	}
+	return true
+}
+
+func (w *World) editObject(id uint64, edit func(object.Editable)) bool {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	if !w.canEdit() {
+		return false
+	}
+
+	obj, ok := w.Objects[id].(object.Editable)
+	if !ok {
+		return false
+	}
+
+	single := w.beginEdit()
+	w.touchObject(obj)
+	edit(obj)
+	if single {
+		w.endEdit()
+	}
+	return true
+}
+
+func (w *World) canEdit() bool {
+	return w.state == WORLD_STATE_PREPARE || w.state == WORLD_STATE_NONE
+}
+
+// Start the edit unless one is in progress, reports whether it was started
+func (w *World) beginEdit() bool {
+	if w.currentEdit != nil {
+		return false
+	}
+	w.currentEdit = &worldEdit{
+		objects:     make(map[uint64]bool),
+		paintBefore: make(map[string][]float32),
+		paintAfter:  make(map[string][]float32),
+	}
+	return true
+}
+
+func (w *World) endEdit() {
+	e := w.currentEdit
+	if e == nil {
+		return
+	}
+	w.currentEdit = nil
+	if len(e.before) == 0 && len(e.paintBefore) == 0 {
+		return
+	}
+
+	e.after = make([]objectState, len(e.before))
+	for i, s := range e.before {
+		e.after[i] = w.captureObject(s.obj)
+	}
+	for name := range e.paintBefore {
+		e.paintAfter[name] = append([]float32(nil), w.fieldPaint[name]...)
+	}
+
+	// New edit drops the undone ones
+	w.edits = append(w.edits[:w.editIndex], e)
+	if len(w.edits) > WorldEditHistory {
+		w.edits = w.edits[len(w.edits)-WorldEditHistory:]
+	}
+	w.editIndex = len(w.edits)
+}
+
+func (w *World) clearEdits() {
+	w.edits = nil
+	w.editIndex = 0
+}
+
+// Objects placed or moved outside of the edits make the history stale
+func (w *World) forgetEdits() {
+	if w.currentEdit == nil && len(w.edits) > 0 {
+		w.clearEdits()
+	}
+}
+
+// Remember the object state before its first change in the current edit
+func (w *World) touchObject(obj object.Movable) {
+	if w.currentEdit.objects[obj.GetID()] {
+		return
+	}
+	w.currentEdit.objects[obj.GetID()] = true
+	w.currentEdit.before = append(w.currentEdit.before, w.captureObject(obj))
+}
+
+func (w *World) touchPaint(name string) []float32 {
+	paint, exists := w.fieldPaint[name]
+	if !exists {
+		paint = make([]float32, w.Width*w.Height)
+		w.fieldPaint[name] = paint
+	}
+	if _, touched := w.currentEdit.paintBefore[name]; !touched {
+		w.currentEdit.paintBefore[name] = append([]float32(nil), paint...)
+	}
+	return paint
+}
+
+func (w *World) captureObject(obj object.Movable) objectState {
+	s := objectState{obj: obj, position: obj.GetPosition()}
+	_, s.placed = w.Objects[obj.GetID()]
+	if editable, ok := obj.(object.Editable); ok {
+		s.restore = editable.Snapshot()
+	}
+	return s
+}
+
+// The squares to restore the objects to are free or taken by the restored objects
+func (w *World) canApplyObjectStates(states []objectState) bool {
+	restored := make(map[uint64]bool, len(states))
+	for _, s := range states {
+		restored[s.obj.GetID()] = true
+	}
+	for _, s := range states {
+		if !s.placed {
+			continue
+		}
+		other := w.Places[s.position.X][s.position.Y]
+		if other != nil && !restored[other.GetID()] {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *World) applyObjectStates(states []objectState) {
+	for _, s := range states {
+		w.takeObject(s.obj)
+	}
+	for _, s := range states {
+		if s.restore != nil {
+			s.restore()
+		}
+		if s.placed {
+			w.Objects[s.obj.GetID()] = s.obj
+			w.Places[s.position.X][s.position.Y] = s.obj
+		}
+	}
+}
+
+// Remove the object regardless of the world state
+func (w *World) takeObject(obj object.Movable) {
+	if _, placed := w.Objects[obj.GetID()]; !placed {
+		return
+	}
+	pos := obj.GetPosition()
+	if w.Places[pos.X][pos.Y] == obj {
+		w.Places[pos.X][pos.Y] = nil
+	}
+	delete(w.Objects, obj.GetID())
+}
+
+func (w *World) applyPaint(paint map[string][]float32) {
+	for name, values := range paint {
+		w.fieldPaint[name] = append([]float32(nil), values...)
+		w.calculateField(name)
+	}
+}
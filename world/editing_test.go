@@ -0,0 +1,54 @@
+package world_test
+
+import (
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/world"
+	"testing"
+	"time"
+)
+
+func TestUndoAfterOutsideChange(t *testing.T) {
+	pos := object.Position{X: 3, Y: 4}
+	tests := []struct {
+		name string
+		// change of the world made outside of the edits after the erase
+		change   func(w *world.World)
+		undone   bool
+		occupant uint64
+	}{
+		{"no change", func(w *world.World) {}, true, 1},
+		{"cell placed on the erased square", func(w *world.World) { cell.New(w, nil, pos) }, false, 2},
+		{"cell placed elsewhere", func(w *world.World) { cell.New(w, nil, object.Position{X: 6, Y: 6}) }, false, 0},
+		{"stepping", func(w *world.World) { w.EnterStepping() }, false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := world.New(10, 10, time.Millisecond)
+			cell.New(w, nil, pos)
+			if erased := w.EraseRect(pos, pos); erased != 1 {
+				t.Fatalf("erased %d objects, want 1", erased)
+			}
+
+			test.change(w)
+
+			if undone := w.Undo(); undone != test.undone {
+				t.Fatalf("undo = %v, want %v", undone, test.undone)
+			}
+			var occupant uint64
+			if obj := w.Places[pos.X][pos.Y]; obj != nil {
+				occupant = obj.GetID()
+			}
+			if occupant != test.occupant {
+				t.Errorf("square is taken by %d, want %d", occupant, test.occupant)
+			}
+			for id, obj := range w.Objects {
+				p := obj.GetPosition()
+				if w.Places[p.X][p.Y] != obj {
+					t.Errorf("object %d is not on its square [%d, %d]", id, p.X, p.Y)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package world
+
+import (
+	"gopher-dish/object"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+const (
+	FIELD_SUNLIGHT = "sunlight"
+	FIELD_MINERALS = "minerals"
+)
+
+// EnvironmentField is a scalar map laid over the world grid.
+// Value is called for every square when the field map is (re)calculated,
+// Drift is called once a year and reports whether the field has changed.
+type EnvironmentField interface {
+	Value(x, y int32, width, height uint32) float64
+	Drift() bool
+}
+
+// LinearGradient changes its value linearly along the Y axis between
+// Begin and End (fractions of the world height). Mirrored gradient
+// turns back at the middle of the band.
+type LinearGradient struct {
+	Begin, End           float64
+	BeginValue, EndValue float64
+	Mirrored             bool
+
+	BeginDrift, EndDrift float64
+}
+
+func (g *LinearGradient) Value(x, y int32, width, height uint32) float64 {
+	begin := int32(math.Round(float64(height) * g.Begin))
+	mid := int32(math.Round(float64(height) * (g.Begin + g.End) / 2))
+	end := int32(math.Round(float64(height) * g.End))
+
+	if y < begin || y >= end {
+		return 0
+	}
+
+	if g.Mirrored && y >= mid {
+		return remap(float64(y), float64(begin), float64(end), g.EndValue, g.BeginValue)
+	}
+	return remap(float64(y), float64(begin), float64(end), g.BeginValue, g.EndValue)
+}
+
+func (g *LinearGradient) Drift() bool {
+	if g.BeginDrift == 0 && g.EndDrift == 0 {
+		return false
+	}
+	if g.Begin+g.BeginDrift >= g.End+g.EndDrift {
+		return false
+	}
+	g.Begin += g.BeginDrift
+	g.End += g.EndDrift
+	return true
+}
+
+// RadialHotspot is a round spot with the value fading from the center to the Radius.
+// X and Y are fractions of the world dimensions, Radius is a fraction of the world height.
+type RadialHotspot struct {
+	X, Y      float64
+	Radius    float64
+	Intensity float64
+
+	DriftX, DriftY float64
+}
+
+func (h *RadialHotspot) Value(x, y int32, width, height uint32) float64 {
+	radius := h.Radius * float64(height)
+	if radius <= 0 {
+		return 0
+	}
+
+	dx := math.Abs(float64(x) - h.X*float64(width))
+	if dx > float64(width)/2 {
+		dx = float64(width) - dx
+	}
+	dy := float64(y) - h.Y*float64(height)
+
+	dist := math.Sqrt(dx*dx + dy*dy)
+	if dist >= radius {
+		return 0
+	}
+	return h.Intensity * (1 - dist/radius)
+}
+
+func (h *RadialHotspot) Drift() bool {
+	if h.DriftX == 0 && h.DriftY == 0 {
+		return false
+	}
+	h.X = math.Mod(h.X+h.DriftX+1, 1)
+	h.Y = math.Max(0, math.Min(1, h.Y+h.DriftY))
+	return true
+}
+
+// NoiseField is a Perlin noise map. Scale is the size of a noise cell in squares,
+// the offset drifts to make the map flow over time.
+type NoiseField struct {
+	Seed      int64
+	Scale     float64
+	Octaves   int
+	Intensity float64
+
+	OffsetX, OffsetY float64
+	DriftX, DriftY   float64
+
+	perm []int
+}
+
+func (n *NoiseField) Value(x, y int32, width, height uint32) float64 {
+	if n.perm == nil {
+		n.perm = noisePermutation(n.Seed)
+	}
+
+	scale := n.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	octaves := n.Octaves
+	if octaves <= 0 {
+		octaves = 1
+	}
+
+	var sum, amp, ampSum float64 = 0, 1, 0
+	freq := 1 / scale
+	for i := 0; i < octaves; i++ {
+		sum += amp * n.perlin((float64(x)+n.OffsetX)*freq, (float64(y)+n.OffsetY)*freq)
+		ampSum += amp
+		amp /= 2
+		freq *= 2
+	}
+
+	return (sum/ampSum + 1) / 2 * n.Intensity
+}
+
+func (n *NoiseField) Drift() bool {
+	if n.DriftX == 0 && n.DriftY == 0 {
+		return false
+	}
+	n.OffsetX += n.DriftX
+	n.OffsetY += n.DriftY
+	return true
+}
+
+func (n *NoiseField) perlin(x, y float64) float64 {
+	xf, yf := math.Floor(x), math.Floor(y)
+	xi, yi := int(xf)&255, int(yf)&255
+	x, y = x-xf, y-yf
+	u, v := noiseFade(x), noiseFade(y)
+
+	p := n.perm
+	aa := p[p[xi]+yi]
+	ab := p[p[xi]+yi+1]
+	ba := p[p[xi+1]+yi]
+	bb := p[p[xi+1]+yi+1]
+
+	return noiseLerp(v,
+		noiseLerp(u, noiseGrad(aa, x, y), noiseGrad(ba, x-1, y)),
+		noiseLerp(u, noiseGrad(ab, x, y-1), noiseGrad(bb, x-1, y-1)),
+	)
+}
+
+// ImageField takes values from the brightness of an image stretched over the world.
+type ImageField struct {
+	Path      string
+	Intensity float64
+	OffsetX   float64
+	DriftX    float64
+
+	pixels [][]float64
+}
+
+func NewImageField(path string, intensity float64) (*ImageField, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	field := &ImageField{Path: path, Intensity: intensity}
+	err = field.Load(f)
+	if err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+func (f *ImageField) Load(reader io.Reader) error {
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	f.pixels = make([][]float64, bounds.Dx())
+	for x := 0; x < bounds.Dx(); x++ {
+		f.pixels[x] = make([]float64, bounds.Dy())
+		for y := 0; y < bounds.Dy(); y++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			f.pixels[x][y] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xFFFF
+		}
+	}
+	return nil
+}
+
+func (f *ImageField) Value(x, y int32, width, height uint32) float64 {
+	if len(f.pixels) == 0 || len(f.pixels[0]) == 0 {
+		return 0
+	}
+
+	sx := (float64(x) + f.OffsetX) / float64(width)
+	sx -= math.Floor(sx)
+	px := int(sx * float64(len(f.pixels)))
+	py := int(float64(y) / float64(height) * float64(len(f.pixels[0])))
+
+	return f.pixels[px][py] * f.Intensity
+}
+
+func (f *ImageField) Drift() bool {
+	if f.DriftX == 0 {
+		return false
+	}
+	f.OffsetX += f.DriftX
+	return true
+}
+
+func (w *World) AddField(name string, field EnvironmentField) {
+	w.Fields[name] = field
+	w.calculateField(name)
+}
+
+func (w *World) RemoveField(name string) {
+	delete(w.Fields, name)
+	delete(w.fieldMaps, name)
+	w.EnvironmentRevision++
+}
+
+func (w *World) GetField(name string) EnvironmentField {
+	return w.Fields[name]
+}
+
+// Painted edits added to the field map column by column, nil if the field isn't painted
+func (w *World) GetFieldPaint(name string) []float32 {
+	return w.fieldPaint[name]
+}
+
+// Restore the saved paint of the field, it must cover the whole world
+func (w *World) SetFieldPaint(name string, paint []float32) bool {
+	if len(paint) != int(w.Width*w.Height) {
+		return false
+	}
+	w.fieldPaint[name] = append([]float32(nil), paint...)
+	w.calculateField(name)
+	return true
+}
+
+func (w *World) GetFieldAtPosition(name string, pos object.Position) byte {
+	fieldMap, exists := w.fieldMaps[name]
+	if !exists {
+		return 0
+	}
+
+	pos.X = (pos.X + int32(w.Width)) % int32(w.Width)
+	if pos.Y < 0 || pos.Y >= int32(w.Height) {
+		return 0
+	}
+	return fieldMap[pos.X][pos.Y]
+}
+
+func (w *World) calculateField(name string) {
+	field, exists := w.Fields[name]
+	if !exists {
+		return
+	}
+
+	paint := w.fieldPaint[name]
+	fieldMap := make([][]byte, w.Width)
+	for x := 0; x < int(w.Width); x++ {
+		fieldMap[x] = make([]byte, w.Height)
+		for y := 0; y < int(w.Height); y++ {
+			value := field.Value(int32(x), int32(y), w.Width, w.Height)
+			if paint != nil {
+				value += float64(paint[x*int(w.Height)+y])
+			}
+			value = math.Round(value)
+			if w.isDroughtAt(name, int32(x), int32(y)) {
+				value = 0
+			} else if value > 255 {
+				value = 255
+			} else if value < 0 {
+				value = 0
+			}
+			fieldMap[x][y] = byte(value)
+		}
+	}
+
+	w.fieldMaps[name] = fieldMap
+	w.EnvironmentRevision++
+}
+
+func (w *World) driftFields() {
+	for name, field := range w.Fields {
+		if field.Drift() {
+			w.calculateField(name)
+		}
+	}
+}
+
+func noisePermutation(seed int64) []int {
+	perm := rand.New(rand.NewSource(seed)).Perm(256)
+	return append(perm, perm...)
+}
+
+func noiseFade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func noiseLerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+func noiseGrad(hash int, x, y float64) float64 {
+	switch hash & 3 {
+	case 0:
+		return x + y
+	case 1:
+		return -x + y
+	case 2:
+		return x - y
+	default:
+		return -x - y
+	}
+}
@@ -0,0 +1,200 @@
+package world
+
+import (
+	"gopher-dish/object"
+	"math/rand"
+)
+
+type WorldEventType string
+
+// World events list
+const (
+	// Kill every cell in the radius
+	EVENT_METEOR WorldEventType = "meteor"
+	// Zero the field (sunlight by default) in the radius for the duration
+	EVENT_DROUGHT WorldEventType = "drought"
+	// Raise the mutation rate for the duration
+	EVENT_MUTATION_STORM WorldEventType = "mutation_storm"
+	// Hurt cells with one genome hash every year for the duration
+	EVENT_PLAGUE WorldEventType = "plague"
+)
+
+type EventConfig struct {
+	Type WorldEventType
+
+	// Year of the scripted event
+	Year uint64
+	// Probability of the random event per year, the center is random as well
+	Chance float64
+
+	X, Y     int32
+	Radius   int32
+	Duration uint64
+
+	// Field dried out by the drought
+	Field string
+	// Additional mutations per reproduction during the storm
+	MutationRate int
+	// Genome hash hit by the plague, the most common one if zero
+	GenomeHash uint64
+	// Health lost by the plague victims every year, zero kills them at once
+	Damage uint32
+}
+
+type WorldEvent struct {
+	EventConfig
+	Began     uint64
+	Remaining uint64
+}
+
+func (w *World) StartEvent(cfg EventConfig) {
+	e := &WorldEvent{EventConfig: cfg, Began: w.Year, Remaining: cfg.Duration}
+
+	switch e.Type {
+	case EVENT_METEOR:
+		w.forEachInRadius(object.Position{X: e.X, Y: e.Y}, e.Radius, func(obj object.Movable) {
+			if lively, ok := obj.(object.Lively); ok && !lively.IsDied() {
+				lively.Die(object.DEATH_EVENT)
+			}
+		})
+		w.Record(STATS_EVENT_BEGIN, e)
+		return
+	case EVENT_DROUGHT:
+		if e.Field == "" {
+			e.Field = FIELD_SUNLIGHT
+		}
+		w.ActiveEvents = append(w.ActiveEvents, e)
+		w.calculateField(e.Field)
+	case EVENT_MUTATION_STORM:
+		w.MutationBoost += e.MutationRate
+		w.ActiveEvents = append(w.ActiveEvents, e)
+	case EVENT_PLAGUE:
+		if e.GenomeHash == 0 {
+			e.GenomeHash = w.mostCommonGenome()
+		}
+		w.ActiveEvents = append(w.ActiveEvents, e)
+		w.infect(e)
+	default:
+		return
+	}
+
+	w.Record(STATS_EVENT_BEGIN, e)
+}
+
+func (w *World) handleEvents() {
+	var active, finished []*WorldEvent
+	for _, e := range w.ActiveEvents {
+		if e.Remaining > 0 {
+			e.Remaining--
+		}
+		if e.Remaining == 0 {
+			finished = append(finished, e)
+			continue
+		}
+		if e.Type == EVENT_PLAGUE {
+			w.infect(e)
+		}
+		active = append(active, e)
+	}
+
+	w.ActiveEvents = active
+	for _, e := range finished {
+		w.stopEvent(e)
+	}
+
+	for _, cfg := range w.Config.Events {
+		if cfg.Chance > 0 {
+			if rand.Float64() >= cfg.Chance {
+				continue
+			}
+			cfg.X = rand.Int31n(int32(w.Width))
+			cfg.Y = rand.Int31n(int32(w.Height))
+		} else if cfg.Year != w.Year {
+			continue
+		}
+		w.StartEvent(cfg)
+	}
+}
+
+func (w *World) stopEvent(e *WorldEvent) {
+	switch e.Type {
+	case EVENT_DROUGHT:
+		w.calculateField(e.Field)
+	case EVENT_MUTATION_STORM:
+		w.MutationBoost -= e.MutationRate
+	}
+	w.Record(STATS_EVENT_END, e)
+}
+
+func (w *World) isDroughtAt(field string, x, y int32) bool {
+	for _, e := range w.ActiveEvents {
+		if e.Type != EVENT_DROUGHT || e.Field != field {
+			continue
+		}
+		if w.distanceSq(object.Position{X: x, Y: y}, object.Position{X: e.X, Y: e.Y}) <= e.Radius*e.Radius {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) infect(e *WorldEvent) {
+	for _, obj := range w.Objects {
+		lively, ok := obj.(object.Lively)
+		if !ok || lively.IsDied() || lively.GetGenomeHash() != e.GenomeHash {
+			continue
+		}
+		if e.Damage == 0 {
+			lively.Die(object.DEATH_EVENT)
+		} else {
+			lively.LoseHealth(e.Damage, object.DEATH_EVENT)
+		}
+	}
+}
+
+func (w *World) mostCommonGenome() (hash uint64) {
+	counts := make(map[uint64]int)
+	best := 0
+	for _, obj := range w.Objects {
+		lively, ok := obj.(object.Lively)
+		if !ok || lively.IsDied() {
+			continue
+		}
+		h := lively.GetGenomeHash()
+		counts[h]++
+		if counts[h] > best {
+			best = counts[h]
+			hash = h
+		}
+	}
+	return
+}
+
+func (w *World) forEachInRadius(center object.Position, radius int32, f func(object.Movable)) {
+	for x := center.X - radius; x <= center.X+radius; x++ {
+		for y := center.Y - radius; y <= center.Y+radius; y++ {
+			if y < 0 || y >= int32(w.Height) {
+				continue
+			}
+			pos := object.Position{X: (x%int32(w.Width) + int32(w.Width)) % int32(w.Width), Y: y}
+			if w.distanceSq(pos, center) > radius*radius {
+				continue
+			}
+			if obj := w.Places[pos.X][pos.Y]; obj != nil {
+				f(obj)
+			}
+		}
+	}
+}
+
+func (w *World) distanceSq(a, b object.Position) int32 {
+	dx := a.X - b.X
+	if dx < 0 {
+		dx = -dx
+	}
+	if dx > int32(w.Width)/2 {
+		dx = int32(w.Width) - dx
+	}
+	dy := a.Y - b.Y
+	return dx*dx + dy*dy
+}
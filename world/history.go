@@ -0,0 +1,87 @@
+package world
+
+import "gopher-dish/object"
+
+const (
+	WorldHistorySize = 4096
+)
+
+// Population state of one tick
+type HistorySample struct {
+	Tick           uint64
+	Population     uint32
+	Living, Dead   uint32
+	MeanEnergy     float32
+	MeanGeneration float32
+	Genomes        uint32
+	// Sunlight band as fractions of the world height
+	SunlightBegin, SunlightEnd float32
+}
+
+// Ring buffer of the last samples
+type History struct {
+	samples []HistorySample
+	next    int
+	full    bool
+
+	genomes map[uint64]struct{}
+}
+
+func NewHistory(size int) *History {
+	return &History{
+		samples: make([]HistorySample, size),
+		genomes: make(map[uint64]struct{}),
+	}
+}
+
+func (h *History) Add(sample HistorySample) {
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Copy of the samples, the oldest first
+func (h *History) Samples() []HistorySample {
+	if !h.full {
+		return append([]HistorySample(nil), h.samples[:h.next]...)
+	}
+	return append(append([]HistorySample(nil), h.samples[h.next:]...), h.samples[:h.next]...)
+}
+
+func (w *World) recordHistory() {
+	sample := HistorySample{Tick: w.Ticks, Population: uint32(len(w.Objects))}
+
+	var energy, generation float64
+	for _, obj := range w.Objects {
+		lively, ok := obj.(object.Lively)
+		if !ok {
+			continue
+		}
+		if lively.IsDied() {
+			sample.Dead++
+			continue
+		}
+		sample.Living++
+		energy += float64(lively.GetEnergy())
+		generation += float64(lively.GetGeneration())
+		w.History.genomes[lively.GetGenomeHash()] = struct{}{}
+	}
+
+	if sample.Living > 0 {
+		sample.MeanEnergy = float32(energy / float64(sample.Living))
+		sample.MeanGeneration = float32(generation / float64(sample.Living))
+	}
+	sample.Genomes = uint32(len(w.History.genomes))
+	for hash := range w.History.genomes {
+		delete(w.History.genomes, hash)
+	}
+
+	if sunlight, ok := w.Fields[FIELD_SUNLIGHT].(*LinearGradient); ok {
+		sample.SunlightBegin = float32(sunlight.Begin)
+		sample.SunlightEnd = float32(sunlight.End)
+	}
+
+	w.History.Add(sample)
+}
@@ -0,0 +1,37 @@
+package world
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gopher-dish/object"
+)
+
+// Counters for the monitoring, births and deaths are updated from the object goroutines
+type Metrics struct {
+	births uint64
+	deaths [object.DEATH_CAUSE_ENUM_SIZE]uint64
+
+	// Phase durations of the last tick
+	PrepareDuration time.Duration
+	HandleDuration  time.Duration
+	RemovalDuration time.Duration
+}
+
+func (m *Metrics) Birth() {
+	atomic.AddUint64(&m.births, 1)
+}
+
+func (m *Metrics) Death(cause object.DeathCause) {
+	if cause < object.DEATH_CAUSE_ENUM_SIZE {
+		atomic.AddUint64(&m.deaths[cause], 1)
+	}
+}
+
+func (m *Metrics) Births() uint64 {
+	return atomic.LoadUint64(&m.births)
+}
+
+func (m *Metrics) Deaths(cause object.DeathCause) uint64 {
+	return atomic.LoadUint64(&m.deaths[cause])
+}
@@ -0,0 +1,150 @@
+package world
+
+import (
+	"gopher-dish/object"
+	"math"
+	"sync"
+)
+
+const (
+	SignalChannels = 4
+
+	WorldSignalDiffusion   = 0.2
+	WorldSignalEvaporation = 0.02
+
+	// Channel is considered empty when its maximum drops below the threshold
+	signalEmptyThreshold = 0.5
+)
+
+type SignalConfig struct {
+	// Part of the difference with neighbour squares exchanged every tick
+	Diffusion float64
+	// Part of the concentration lost every tick
+	Evaporation float64
+}
+
+type signalLayer struct {
+	values []float32
+	buffer []float32
+	active bool
+}
+
+func (w *World) EmitSignal(channel int, pos object.Position, value float64) bool {
+	if channel < 0 || channel >= SignalChannels {
+		return false
+	}
+
+	pos.X = (pos.X + int32(w.Width)) % int32(w.Width)
+	if pos.Y < 0 || pos.Y >= int32(w.Height) {
+		return false
+	}
+
+	layer := &w.signals[channel]
+	layer.values[w.signalIndex(pos.X, pos.Y)] += float32(value)
+	layer.active = true
+	return true
+}
+
+func (w *World) GetSignalAtPosition(channel int, pos object.Position) byte {
+	if channel < 0 || channel >= SignalChannels {
+		return 0
+	}
+
+	pos.X = (pos.X + int32(w.Width)) % int32(w.Width)
+	if pos.Y < 0 || pos.Y >= int32(w.Height) {
+		return 0
+	}
+
+	value := math.Round(float64(w.signals[channel].values[w.signalIndex(pos.X, pos.Y)]))
+	if value > 255 {
+		return 255
+	}
+	return byte(value)
+}
+
+func (w *World) initSignals() {
+	for i := range w.signals {
+		w.signals[i].values = make([]float32, w.Width*w.Height)
+		w.signals[i].buffer = make([]float32, w.Width*w.Height)
+	}
+}
+
+func (w *World) signalIndex(x, y int32) int {
+	return int(x)*int(w.Height) + int(y)
+}
+
+// Diffuse and evaporate all non-empty channels, the grid is split into column chunks handled in parallel
+func (w *World) diffuseSignals() {
+	chunkWidth := (int(w.Width) + w.chunkCount - 1) / w.chunkCount
+
+	for ch := range w.signals {
+		layer := &w.signals[ch]
+		if !layer.active {
+			continue
+		}
+
+		cfg := w.Config.Signals[ch]
+		diffusion := float32(cfg.Diffusion)
+		keep := float32(1 - cfg.Evaporation)
+
+		var wg sync.WaitGroup
+		maximums := make([]float32, w.chunkCount)
+
+		for chunk := 0; chunk < w.chunkCount; chunk++ {
+			fromX, toX := chunk*chunkWidth, (chunk+1)*chunkWidth
+			if toX > int(w.Width) {
+				toX = int(w.Width)
+			}
+			if fromX >= toX {
+				continue
+			}
+
+			wg.Add(1)
+			go func(chunk, fromX, toX int) {
+				defer wg.Done()
+				var max float32
+				for x := fromX; x < toX; x++ {
+					left := (x + int(w.Width) - 1) % int(w.Width)
+					right := (x + 1) % int(w.Width)
+					for y := 0; y < int(w.Height); y++ {
+						i := x*int(w.Height) + y
+						center := layer.values[i]
+
+						// walls reflect the signal back
+						up, down := center, center
+						if y > 0 {
+							up = layer.values[i-1]
+						}
+						if y < int(w.Height)-1 {
+							down = layer.values[i+1]
+						}
+						neighbours := layer.values[left*int(w.Height)+y] + layer.values[right*int(w.Height)+y] + up + down
+
+						value := (center + diffusion*(neighbours/4-center)) * keep
+						layer.buffer[i] = value
+						if value > max {
+							max = value
+						}
+					}
+				}
+				maximums[chunk] = max
+			}(chunk, fromX, toX)
+		}
+
+		wg.Wait()
+		layer.values, layer.buffer = layer.buffer, layer.values
+
+		layer.active = false
+		for _, max := range maximums {
+			if max >= signalEmptyThreshold {
+				layer.active = true
+				break
+			}
+		}
+		if !layer.active {
+			for i := range layer.values {
+				layer.values[i] = 0
+			}
+		}
+	}
+}
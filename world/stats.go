@@ -0,0 +1,137 @@
+package world
+
+import (
+	"encoding/json"
+	"gopher-dish/object"
+	"io"
+	"sort"
+)
+
+const (
+	STATS_SUMMARY     = "summary"
+	STATS_EVENT_BEGIN = "event_begin"
+	STATS_EVENT_END   = "event_end"
+	STATS_TRACE       = "trace"
+	STATS_GENOMES     = "genomes"
+)
+
+const (
+	// Most populated genomes written to the stats stream
+	StatsReportGenomes = 16
+)
+
+type StatsRecord struct {
+	Tick  uint64
+	Year  uint64
+	Epoch uint64
+	Kind  string
+	Data  any
+}
+
+type StatsSummary struct {
+	Population int
+	Framerate  uint
+}
+
+type GenomeStats struct {
+	Hash           uint64
+	Population     int
+	FunctionalSize int
+}
+
+type GenomesReport struct {
+	// Mean over the living objects
+	MeanFunctionalSize float64
+	Genomes            []GenomeStats
+}
+
+// Static analysis of the genomes, the world doesn't know the commands.
+// Objects without genome are skipped
+type GenomeAnalyzer interface {
+	FunctionalSize(obj object.Movable) (hash uint64, size int, ok bool)
+}
+
+// StatsStream writes world stats records as JSON lines
+type StatsStream struct {
+	encoder *json.Encoder
+}
+
+func NewStatsStream(writer io.Writer) *StatsStream {
+	return &StatsStream{encoder: json.NewEncoder(writer)}
+}
+
+func (s *StatsStream) Write(record StatsRecord) error {
+	return s.encoder.Encode(record)
+}
+
+func (w *World) Record(kind string, data any) {
+	if w.Stats == nil {
+		return
+	}
+
+	w.Stats.Write(StatsRecord{
+		Tick:  w.Ticks,
+		Year:  w.Year,
+		Epoch: w.Epoch,
+		Kind:  kind,
+		Data:  data,
+	})
+}
+
+func (w *World) recordSummary() {
+	if w.Stats == nil || w.Config.StatsInterval == 0 || w.Ticks%w.Config.StatsInterval != 0 {
+		return
+	}
+
+	w.Record(STATS_SUMMARY, StatsSummary{
+		Population: len(w.Objects),
+		Framerate:  w.Framerate,
+	})
+}
+
+func (w *World) recordGenomes() {
+	if w.Stats == nil || w.Analyzer == nil || w.Config.StatsInterval == 0 || w.Ticks%w.Config.StatsInterval != 0 {
+		return
+	}
+
+	genomes := make(map[uint64]*GenomeStats)
+	var report GenomesReport
+	var living int
+	for _, obj := range w.Objects {
+		if lively, ok := obj.(object.Lively); ok && lively.IsDied() {
+			continue
+		}
+		hash, size, ok := w.Analyzer.FunctionalSize(obj)
+		if !ok {
+			continue
+		}
+		living++
+		report.MeanFunctionalSize += float64(size)
+
+		g, exists := genomes[hash]
+		if !exists {
+			g = &GenomeStats{Hash: hash, FunctionalSize: size}
+			genomes[hash] = g
+		}
+		g.Population++
+	}
+	if living == 0 {
+		return
+	}
+	report.MeanFunctionalSize /= float64(living)
+
+	for _, g := range genomes {
+		report.Genomes = append(report.Genomes, *g)
+	}
+	sort.Slice(report.Genomes, func(i, j int) bool {
+		if report.Genomes[i].Population != report.Genomes[j].Population {
+			return report.Genomes[i].Population > report.Genomes[j].Population
+		}
+		return report.Genomes[i].Hash < report.Genomes[j].Hash
+	})
+	if len(report.Genomes) > StatsReportGenomes {
+		report.Genomes = report.Genomes[:StatsReportGenomes]
+	}
+
+	w.Record(STATS_GENOMES, report)
+}
@@ -0,0 +1,150 @@
+package world
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Genomes with the most executed commands written to the stats stream
+	TracerReportGenomes = 16
+)
+
+// Tracer counts executed commands of the world, the VM calls it only
+// when it is set so the disabled tracer costs nothing
+type Tracer struct {
+	names []string
+
+	commands []uint64
+	taken    []uint64
+	skipped  []uint64
+
+	// Per genome counters are kept for the stats interval only
+	genomes *sync.Map
+}
+
+type BranchStats struct {
+	Taken   uint64
+	Skipped uint64
+}
+
+type TraceReport struct {
+	Commands map[string]uint64
+	Branches map[string]BranchStats
+	Genomes  map[uint64]map[string]uint64
+}
+
+// Names of the commands define the size of the histograms
+func NewTracer(names []string) *Tracer {
+	return &Tracer{
+		names:    names,
+		commands: make([]uint64, len(names)),
+		taken:    make([]uint64, len(names)),
+		skipped:  make([]uint64, len(names)),
+		genomes:  new(sync.Map),
+	}
+}
+
+func (t *Tracer) Command(hash uint64, cmd uint64) {
+	if cmd >= uint64(len(t.names)) {
+		return
+	}
+	atomic.AddUint64(&t.commands[cmd], 1)
+
+	counters, ok := t.genomes.Load(hash)
+	if !ok {
+		counters, _ = t.genomes.LoadOrStore(hash, make([]uint64, len(t.names)))
+	}
+	atomic.AddUint64(&counters.([]uint64)[cmd], 1)
+}
+
+func (t *Tracer) Branch(cmd uint64, taken bool) {
+	if cmd >= uint64(len(t.names)) {
+		return
+	}
+	if taken {
+		atomic.AddUint64(&t.taken[cmd], 1)
+	} else {
+		atomic.AddUint64(&t.skipped[cmd], 1)
+	}
+}
+
+func (t *Tracer) Names() []string {
+	return t.names
+}
+
+// Executions per command since the tracer creation
+func (t *Tracer) Commands() []uint64 {
+	counts := make([]uint64, len(t.commands))
+	for i := range t.commands {
+		counts[i] = atomic.LoadUint64(&t.commands[i])
+	}
+	return counts
+}
+
+func (t *Tracer) Report() TraceReport {
+	report := TraceReport{
+		Commands: make(map[string]uint64),
+		Branches: make(map[string]BranchStats),
+		Genomes:  make(map[uint64]map[string]uint64),
+	}
+
+	for i, count := range t.Commands() {
+		if count > 0 {
+			report.Commands[t.names[i]] = count
+		}
+		taken, skipped := atomic.LoadUint64(&t.taken[i]), atomic.LoadUint64(&t.skipped[i])
+		if taken > 0 || skipped > 0 {
+			report.Branches[t.names[i]] = BranchStats{Taken: taken, Skipped: skipped}
+		}
+	}
+
+	type genomeTotal struct {
+		hash     uint64
+		total    uint64
+		counters []uint64
+	}
+	var totals []genomeTotal
+	t.genomes.Range(func(key, value any) bool {
+		g := genomeTotal{hash: key.(uint64), counters: value.([]uint64)}
+		for i := range g.counters {
+			g.total += atomic.LoadUint64(&g.counters[i])
+		}
+		totals = append(totals, g)
+		return true
+	})
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].total > totals[j].total
+	})
+	if len(totals) > TracerReportGenomes {
+		totals = totals[:TracerReportGenomes]
+	}
+
+	for _, g := range totals {
+		counts := make(map[string]uint64)
+		for i := range g.counters {
+			if count := atomic.LoadUint64(&g.counters[i]); count > 0 {
+				counts[t.names[i]] = count
+			}
+		}
+		report.Genomes[g.hash] = counts
+	}
+
+	return report
+}
+
+func (t *Tracer) resetGenomes() {
+	t.genomes = new(sync.Map)
+}
+
+func (w *World) recordTrace() {
+	if w.Tracer == nil || w.Config.StatsInterval == 0 || w.Ticks%w.Config.StatsInterval != 0 {
+		return
+	}
+
+	if w.Stats != nil {
+		w.Record(STATS_TRACE, w.Tracer.Report())
+	}
+	w.Tracer.resetGenomes()
+}
@@ -2,7 +2,6 @@ package world
 
 import (
 	"gopher-dish/object"
-	"math"
 	"math/rand"
 	"runtime"
 	"sync"
@@ -60,12 +59,19 @@ type World struct {
 	Year  uint64
 	Epoch uint64
 
-	Trend         WorldEpochTrend
-	SunlightBegin float64
-	SunlightEnd   float64
+	Trend WorldEpochTrend
 
-	Sunlight [][]byte
-	Minerals [][]byte
+	Config        Config
+	Stats         *StatsStream
+	Tracer        *Tracer
+	Analyzer      GenomeAnalyzer
+	History       *History
+	Metrics       Metrics
+	ActiveEvents  []*WorldEvent
+	MutationBoost int
+
+	Fields              map[string]EnvironmentField
+	EnvironmentRevision uint64
 
 	Objects          map[uint64]object.Movable
 	ObjectsIdCounter uint64
@@ -79,6 +85,14 @@ type World struct {
 	chunkCount      int
 	objPerChunk     int
 
+	fieldMaps  map[string][][]byte
+	fieldPaint map[string][]float32
+	signals    [SignalChannels]signalLayer
+
+	currentEdit *worldEdit
+	edits       []*worldEdit
+	editIndex   int
+
 	lastTickTime time.Time
 }
 
@@ -93,13 +107,29 @@ func New(width, height uint32, tickInterval time.Duration) *World {
 	}
 	w.Objects = make(map[uint64]object.Movable)
 
-	w.SunlightBegin = WorldSunlightBeginPos
-	w.SunlightEnd = WorldSunlightEndPos
+	w.Config = DefaultConfig()
+
+	w.Fields = make(map[string]EnvironmentField)
+	w.fieldMaps = make(map[string][][]byte)
+	w.fieldPaint = make(map[string][]float32)
 
-	w.calculateSunlight()
-	w.calculateMinerals()
+	w.AddField(FIELD_SUNLIGHT, &LinearGradient{
+		Begin:    WorldSunlightBeginPos,
+		End:      WorldSunlightEndPos,
+		EndValue: WorldSunlightValue * WorldSunlightMultiplier,
+		Mirrored: true,
+	})
+	w.AddField(FIELD_MINERALS, &LinearGradient{
+		Begin:      WorldMineralsBeginPos,
+		End:        WorldMineralsEndPos,
+		BeginValue: WorldMineralsBeginValue * WorldMineralsMultiplier,
+		EndValue:   WorldMineralsEndValue * WorldMineralsMultiplier,
+	})
+
+	w.History = NewHistory(WorldHistorySize)
 
 	w.chunkCount = runtime.NumCPU()
+	w.initSignals()
 	w.Paused = true
 
 	return w
@@ -110,6 +140,16 @@ func (w *World) ReserveID() uint64 {
 	return w.ObjectsIdCounter
 }
 
+// Let the commands stepped out of the tick move and remove objects like in the prepare phase,
+// the world that never ticked is still in no state. Call it with the world stopped.
+// The step changes the world, so the edits can't be undone after it
+func (w *World) EnterStepping() {
+	if w.state == WORLD_STATE_NONE {
+		w.state = WORLD_STATE_PREPARE
+	}
+	w.forgetEdits()
+}
+
 func (w *World) RemoveObject(id uint64) {
 	switch w.state {
 	case WORLD_STATE_PREPARE:
@@ -133,6 +173,7 @@ func (w *World) PlaceObject(obj object.Movable, pos object.Position) bool {
 		return false
 	}
 
+	w.forgetEdits()
 	w.Objects[obj.GetID()] = obj
 	w.Places[pos.X][pos.Y] = obj
 
@@ -153,6 +194,7 @@ func (w *World) MoveObject(obj object.Movable, pos object.Position) bool {
 		return false
 	}
 
+	w.forgetEdits()
 	currentPos := obj.GetPosition()
 	w.Places[currentPos.X][currentPos.Y] = nil
 	w.Places[pos.X][pos.Y] = obj
@@ -160,6 +202,39 @@ func (w *World) MoveObject(obj object.Movable, pos object.Position) bool {
 	return true
 }
 
+func (w *World) MoveObjects(objs []object.Movable, positions []object.Position) bool {
+	if w.state != WORLD_STATE_PREPARE || len(objs) != len(positions) {
+		return false
+	}
+
+	moving := make(map[uint64]bool, len(objs))
+	for _, obj := range objs {
+		moving[obj.GetID()] = true
+	}
+
+	for i := range positions {
+		positions[i].X = (positions[i].X + int32(w.Width)) % int32(w.Width)
+		pos := positions[i]
+		if pos.Y < 0 || pos.Y >= int32(w.Height) {
+			return false
+		}
+		if other := w.Places[pos.X][pos.Y]; other != nil && !moving[other.GetID()] {
+			return false
+		}
+	}
+
+	w.forgetEdits()
+	for _, obj := range objs {
+		currentPos := obj.GetPosition()
+		w.Places[currentPos.X][currentPos.Y] = nil
+	}
+	for i, obj := range objs {
+		w.Places[positions[i].X][positions[i].Y] = obj
+	}
+
+	return true
+}
+
 func (w *World) GetObject(id uint64) object.Movable {
 	obj, exists := w.Objects[id]
 
@@ -224,23 +299,15 @@ func (w *World) GetFreePlace(pos object.Position) (object.Position, bool) {
 }
 
 func (w *World) GetSunlightAtPosition(pos object.Position) byte {
-	pos.X = (pos.X + int32(w.Width)) % int32(w.Width)
-	if pos.Y < 0 || pos.Y >= int32(w.Height) {
-		return 0
-	}
-	return w.Sunlight[pos.X][pos.Y]
+	return w.GetFieldAtPosition(FIELD_SUNLIGHT, pos)
 }
 
 func (w *World) GetMineralsAtPosition(pos object.Position) byte {
-	pos.X = (pos.X + int32(w.Width)) % int32(w.Width)
-	if pos.Y < 0 || pos.Y >= int32(w.Height) {
-		return 0
-	}
-	return w.Minerals[pos.X][pos.Y]
+	return w.GetFieldAtPosition(FIELD_MINERALS, pos)
 }
 
 func (w *World) GetObjectAtPosition(pos object.Position) object.Movable {
-	if w.state != WORLD_STATE_PREPARE {
+	if w.state != WORLD_STATE_PREPARE && w.state != WORLD_STATE_NONE {
 		return nil
 	}
 
@@ -267,6 +334,11 @@ func (w *World) Handle() {
 
 	w.PlacesDrawMux.Lock()
 
+	// Edits can't be undone after the world has changed
+	if len(w.edits) > 0 {
+		w.clearEdits()
+	}
+
 	var yearChanged, epochChanged bool
 
 	w.Ticks++
@@ -280,28 +352,20 @@ func (w *World) Handle() {
 			w.Epoch++
 		}
 
-		switch w.Trend {
-		case TREND_WARM:
-			if w.SunlightBegin > -1 {
-				w.SunlightBegin -= WORLD_TREND_OFFSET
-			}
-			if w.SunlightEnd < 1.5 {
-				w.SunlightEnd += WORLD_TREND_OFFSET
-			}
-			w.calculateSunlight()
-		case TREND_COLD:
-			if w.SunlightBegin < w.SunlightEnd {
-				w.SunlightBegin += WORLD_TREND_OFFSET
-				w.SunlightEnd -= WORLD_TREND_OFFSET
-			}
-			w.calculateSunlight()
-		}
+		w.handleEvents()
+		w.applyTrend()
+		w.driftFields()
 	}
 
+	w.diffuseSignals()
+
+	phaseBegin := time.Now()
 	w.state = WORLD_STATE_PREPARE
 	for _, o := range w.Objects {
 		o.Prepare()
 	}
+	w.Metrics.PrepareDuration = time.Since(phaseBegin)
+	phaseBegin = time.Now()
 
 	var wg sync.WaitGroup
 	wg.Add(len(w.Objects))
@@ -318,6 +382,8 @@ func (w *World) Handle() {
 	wg.Wait()
 	close(w.objectsToRemove)
 	removedObjects := 0
+	w.Metrics.HandleDuration = time.Since(phaseBegin)
+	phaseBegin = time.Now()
 
 	w.state = WORLD_STATE_PREPARE
 
@@ -325,54 +391,45 @@ func (w *World) Handle() {
 		w.removeObject(id)
 		removedObjects++
 	}
+	w.Metrics.RemovalDuration = time.Since(phaseBegin)
+
+	w.recordHistory()
+	w.recordSummary()
+	w.recordGenomes()
+	w.recordTrace()
 
 	w.PlacesDrawMux.Unlock()
 
 	<-w.ticker.C
+
+	// Readers of the framerate hold the lock
+	w.PlacesDrawMux.Lock()
 	w.Framerate = uint(1000000 / (time.Since(w.lastTickTime).Microseconds() + 1))
+	w.PlacesDrawMux.Unlock()
 	w.lastTickTime = time.Now()
 }
 
-func (w *World) calculateSunlight() {
-	sunlightBegin := int(math.Round(float64(w.Height) * w.SunlightBegin))
-	sunlightMid := int(math.Round(float64(w.Height) * (w.SunlightBegin + w.SunlightEnd) / 2))
-	sunlightEnd := int(math.Round(float64(w.Height) * w.SunlightEnd))
-
-	w.Sunlight = make([][]byte, w.Width)
-	for x := 0; x < int(w.Width); x++ {
-		w.Sunlight[x] = make([]byte, w.Height)
-
-		y := sunlightBegin
-		if y < 0 {
-			y = 0
-		}
-		for ; y < sunlightMid; y++ {
-			sunlightValue := remap(float64(y), float64(sunlightBegin), float64(sunlightEnd), 0, WorldSunlightValue)
-			w.Sunlight[x][y] = byte(math.Round(sunlightValue * WorldSunlightMultiplier))
-		}
+func (w *World) applyTrend() {
+	sunlight, ok := w.Fields[FIELD_SUNLIGHT].(*LinearGradient)
+	if !ok {
+		return
+	}
 
-		end := sunlightEnd
-		if end > int(w.Height) {
-			end = int(w.Height)
+	switch w.Trend {
+	case TREND_WARM:
+		if sunlight.Begin > -1 {
+			sunlight.Begin -= WORLD_TREND_OFFSET
 		}
-		for y = sunlightMid; y < end; y++ {
-			sunlightValue := remap(float64(y), float64(sunlightBegin), float64(sunlightEnd), WorldSunlightValue, 0)
-			w.Sunlight[x][y] = byte(math.Round(sunlightValue * WorldSunlightMultiplier))
+		if sunlight.End < 1.5 {
+			sunlight.End += WORLD_TREND_OFFSET
 		}
-	}
-}
-
-func (w *World) calculateMinerals() {
-	mineralsBegin := uint32(math.Round(float64(w.Height) * WorldMineralsBeginPos))
-	mineralsEnd := uint32(math.Round(float64(w.Height) * WorldMineralsEndPos))
-
-	w.Minerals = make([][]byte, w.Width)
-	for x := 0; x < int(w.Width); x++ {
-		w.Minerals[x] = make([]byte, w.Height)
-		for y := mineralsBegin; y < mineralsEnd; y++ {
-			mineralsCount := remap(float64(y), float64(mineralsBegin), float64(mineralsEnd), WorldMineralsBeginValue, WorldMineralsEndValue)
-			w.Minerals[x][y] = byte(math.Round(mineralsCount * WorldMineralsMultiplier))
+		w.calculateField(FIELD_SUNLIGHT)
+	case TREND_COLD:
+		if sunlight.Begin < sunlight.End {
+			sunlight.Begin += WORLD_TREND_OFFSET
+			sunlight.End -= WORLD_TREND_OFFSET
 		}
+		w.calculateField(FIELD_SUNLIGHT)
 	}
 }
 
@@ -393,6 +450,7 @@ func (w *World) removeObject(id uint64) {
 		return
 	}
 
+	w.forgetEdits()
 	w.Places[pos.X][pos.Y] = nil
 	delete(w.Objects, id)
 }
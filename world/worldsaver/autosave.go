@@ -0,0 +1,217 @@
+package worldsaver
+
+import (
+	"compress/gzip"
+	"fmt"
+	"gopher-dish/world"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	CheckpointPrefix    = "checkpoint-"
+	CheckpointExtension = ".gdw"
+	DefaultKeep         = 5
+)
+
+// Saves the world every EveryTicks ticks or EveryYears years into Dir and keeps the last Keep checkpoints,
+// the world is encoded to the memory between ticks, compressed and written to the disk in the background
+type Autosaver struct {
+	Dir        string
+	EveryTicks uint64
+	EveryYears uint64
+	Keep       int
+
+	started            bool
+	lastTick, lastYear uint64
+
+	writeMux sync.Mutex
+	pending  sync.WaitGroup
+}
+
+// Remember the tick and the year the world starts from, so the checkpoints
+// land on the multiples of the intervals. Call it before the world loop
+func (a *Autosaver) Start(w *world.World) {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	a.started = true
+	a.lastTick, a.lastYear = w.Ticks, w.Year
+}
+
+// Called after every world tick from the world goroutine,
+// the first call starts the autosaver unless Start did it
+func (a *Autosaver) Check(w *world.World) {
+	w.PlacesDrawMux.Lock()
+	tick, year := w.Ticks, w.Year
+	w.PlacesDrawMux.Unlock()
+
+	if !a.started {
+		a.started = true
+		a.lastTick, a.lastYear = tick, year
+		return
+	}
+
+	// the checkpoint is due when the tick or the year crosses the multiple of the interval
+	due := a.EveryTicks != 0 && tick/a.EveryTicks > a.lastTick/a.EveryTicks
+	due = due || a.EveryYears != 0 && year/a.EveryYears > a.lastYear/a.EveryYears
+	a.lastTick, a.lastYear = tick, year
+	if !due {
+		return
+	}
+
+	saved, err := snapshot(w)
+	if err != nil {
+		fmt.Println("Autosave failed:", err)
+		return
+	}
+
+	a.pending.Add(1)
+	go func() {
+		defer a.pending.Done()
+		a.writeMux.Lock()
+		defer a.writeMux.Unlock()
+
+		path := filepath.Join(a.Dir, fmt.Sprintf("%s%012d%s", CheckpointPrefix, tick, CheckpointExtension))
+		err := WriteAtomic(path, func(writer io.Writer) error {
+			zw, err := gzip.NewWriterLevel(writer, gzip.BestSpeed)
+			if err != nil {
+				return err
+			}
+			if _, err = zw.Write(saved); err != nil {
+				return err
+			}
+			return zw.Close()
+		})
+		if err != nil {
+			fmt.Println("Autosave failed:", err)
+			return
+		}
+		if err := a.rotate(); err != nil {
+			fmt.Println("Can't remove old checkpoints:", err)
+		}
+	}()
+}
+
+// Wait for the checkpoints being written
+func (a *Autosaver) Wait() {
+	a.pending.Wait()
+}
+
+func (a *Autosaver) rotate() error {
+	keep := a.Keep
+	if keep < 1 {
+		keep = DefaultKeep
+	}
+
+	checkpoints, err := Checkpoints(a.Dir)
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(checkpoints); i++ {
+		if err := os.Remove(checkpoints[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save the world to the file holding the world lock while it is written,
+// the format and compression are taken from the path extensions
+func SaveFile(w *world.World, path string) error {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+	return WriteAtomic(path, func(writer io.Writer) error {
+		return SaveAs(w, writer, FormatByPath(path), CompressionByPath(path))
+	})
+}
+
+// Write the temporary file next to the path and rename it,
+// so the path holds either the old or the new content after a crash
+func WriteAtomic(path string, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	// CreateTemp makes the file readable by the owner only, the save is made readable by everyone
+	err = f.Chmod(0644)
+	if err == nil {
+		err = write(f)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// persist the rename, not every platform can sync a directory
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Checkpoint files of the directory, the newest first
+func Checkpoints(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, CheckpointPrefix) && strings.HasSuffix(name, CheckpointExtension) {
+			checkpoints = append(checkpoints, filepath.Join(dir, name))
+		}
+	}
+	// the tick is zero padded, so the names sort by the tick
+	sort.Sort(sort.Reverse(sort.StringSlice(checkpoints)))
+	return checkpoints, nil
+}
+
+// Load the newest checkpoint of the directory that loads without errors
+func Resume(dir string) (*world.World, string, error) {
+	checkpoints, err := Checkpoints(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, path := range checkpoints {
+		w, err := loadCheckpoint(path)
+		if err != nil {
+			fmt.Printf("Skipping checkpoint %s: %v\n", path, err)
+			continue
+		}
+		return w, path, nil
+	}
+	return nil, "", fmt.Errorf("no valid checkpoints in %s", dir)
+}
+
+func loadCheckpoint(path string) (*world.World, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
@@ -0,0 +1,123 @@
+package worldsaver
+
+import (
+	"fmt"
+	"gopher-dish/world"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAutosaverTicks(t *testing.T) {
+	tests := []struct {
+		name        string
+		start, stop uint64
+		every       uint64
+		want        []uint64
+	}{
+		{"new world", 0, 250, 100, []uint64{200, 100}},
+		{"resumed on the multiple", 400, 650, 100, []uint64{600, 500}},
+		{"resumed between the multiples", 399, 520, 100, []uint64{500, 400}},
+		{"every tick", 0, 3, 1, []uint64{3, 2, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := world.New(4, 4, time.Microsecond)
+			w.Ticks = test.start
+			w.Paused = false
+			a := &Autosaver{Dir: t.TempDir(), EveryTicks: test.every, Keep: 10}
+
+			a.Start(w)
+			for w.Ticks < test.stop {
+				w.Handle()
+				a.Check(w)
+			}
+			a.Wait()
+
+			checkpoints, err := Checkpoints(a.Dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, tick := range test.want {
+				want = append(want, filepath.Join(a.Dir, fmt.Sprintf("%s%012d%s", CheckpointPrefix, tick, CheckpointExtension)))
+			}
+			if !reflect.DeepEqual(checkpoints, want) {
+				t.Errorf("checkpoints %v, want %v", checkpoints, want)
+			}
+		})
+	}
+}
+
+func TestWriteAtomicMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "world.gdw")
+	err := WriteAtomic(path, func(writer io.Writer) error {
+		_, err := writer.Write([]byte("data"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0644 {
+		t.Errorf("mode %v, want 0644", mode)
+	}
+}
+
+// Damaged checkpoints are skipped for the older ones
+func TestResume(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "world-v5.gdw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged := append([]byte(nil), fixture...)
+	damageCell(t, damaged, 1, func(r *wCellRecord) { r.Position.X = 15 })
+
+	tests := []struct {
+		name string
+		// checkpoints from the oldest
+		files [][]byte
+		// index of the resumed checkpoint, -1 for none
+		resumed int
+	}{
+		{"valid", [][]byte{fixture, fixture}, 1},
+		{"newest damaged", [][]byte{fixture, damaged}, 0},
+		{"truncated", [][]byte{fixture, fixture[:100]}, 0},
+		{"all damaged", [][]byte{damaged}, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var paths []string
+			for i, data := range test.files {
+				path := filepath.Join(dir, fmt.Sprintf("%s%012d%s", CheckpointPrefix, i+1, CheckpointExtension))
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, path)
+			}
+
+			w, path, err := Resume(dir)
+			if test.resumed < 0 {
+				if err == nil {
+					t.Errorf("resumed %s, want an error", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != paths[test.resumed] || len(w.Objects) != 3 {
+				t.Errorf("resumed %s with %d objects, want %s", path, len(w.Objects), paths[test.resumed])
+			}
+		})
+	}
+}
@@ -0,0 +1,444 @@
+package worldsaver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/utils/genasm"
+	"gopher-dish/world"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// Largest world side accepted from the JSON
+	MaxJSONWorldSide = 1 << 14
+)
+
+// Environment field kinds list
+const (
+	FIELD_KIND_LINEAR = "linear"
+	FIELD_KIND_RADIAL = "radial"
+	FIELD_KIND_NOISE  = "noise"
+	FIELD_KIND_IMAGE  = "image"
+)
+
+// World of the JSON file. The JSON Lines file has the world without cells on the first line
+// and one cell per line after it
+type JSONWorld struct {
+	Width, Height uint32
+
+	Ticks uint64
+	Year  uint64
+	Epoch uint64
+	Trend world.WorldEpochTrend
+
+	ObjectsIdCounter uint64
+	// Missing values of the config are the default ones
+	Config *world.Config
+	// Default fields are kept if the list is missing
+	Fields []JSONField
+	Cells  []JSONCell
+}
+
+type JSONField struct {
+	Name string
+	Kind string
+	// Parameters of the field kind
+	Field json.RawMessage
+	// Field map in rows from the top, calculated from the parameters and ignored by the load
+	Values []int
+	// Painted edits added to the parameters column by column, missing if the field isn't painted
+	Paint []float32
+}
+
+type JSONGenome struct {
+	// Calculated from the code by the load
+	Hash   uint64
+	Traits cell.Traits
+	// Hex of the code bytes, the disassembly is assembled if the code is missing
+	Code        string
+	Disassembly string
+}
+
+type JSONCell struct {
+	Id           uint64
+	Generation   uint64
+	ParentsChain object.ParentsChain
+
+	Age    uint32
+	Health uint32
+	Energy uint32
+	Weight byte
+
+	Died   bool
+	Picked bool
+
+	Genome JSONGenome
+	Brain  cell.Brain
+
+	Bagage         [cell.BagageSize]uint64
+	BagageSelected uint32
+	BagageFullness uint32
+
+	Bonds [cell.BondsCount]uint64
+
+	Position object.Position
+	Rotation object.Rotation
+}
+
+// Save the world as one JSON document or as JSON Lines, the cells are sorted by the id
+// and encoded straight to the writer
+func SaveJSON(w *world.World, writer io.Writer, lines bool) error {
+	doc, err := jsonWorld(w)
+	if err != nil {
+		return err
+	}
+	cells := make([]*cell.Cell, 0, len(w.Objects))
+	for _, obj := range w.Objects {
+		if c, ok := obj.(*cell.Cell); ok {
+			cells = append(cells, c)
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i].Name < cells[j].Name })
+
+	buf := bufio.NewWriter(writer)
+	if lines {
+		encoder := json.NewEncoder(buf)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		for _, c := range cells {
+			if err := encoder.Encode(NewJSONCell(c)); err != nil {
+				return err
+			}
+		}
+		return buf.Flush()
+	}
+
+	// the document ends with the empty cells list, the cells are put into it one by one
+	doc.Cells = []JSONCell{}
+	head, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if len(cells) == 0 {
+		buf.Write(head)
+		buf.WriteString("\n")
+		return buf.Flush()
+	}
+	buf.Write(bytes.TrimSuffix(head, []byte("]\n}")))
+	for i, c := range cells {
+		data, err := json.MarshalIndent(NewJSONCell(c), "    ", "  ")
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n    ")
+		buf.Write(data)
+	}
+	buf.WriteString("\n  ]\n}\n")
+	return buf.Flush()
+}
+
+// Load the world of the JSON document or JSON Lines, cells of the document
+// and of the following lines are both accepted
+func LoadJSON(reader io.Reader) (*world.World, error) {
+	decoder := json.NewDecoder(reader)
+	config := world.DefaultConfig()
+	doc := JSONWorld{Config: &config}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for line := 2; ; line++ {
+		var c JSONCell
+		err := decoder.Decode(&c)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		doc.Cells = append(doc.Cells, c)
+	}
+
+	return doc.World()
+}
+
+// Build the world checking the cells fit it
+func (doc *JSONWorld) World() (*world.World, error) {
+	if doc.Width == 0 || doc.Height == 0 || doc.Width > MaxJSONWorldSide || doc.Height > MaxJSONWorldSide {
+		return nil, fmt.Errorf("world size %dx%d is out of 1-%d", doc.Width, doc.Height, MaxJSONWorldSide)
+	}
+
+	w := world.New(doc.Width, doc.Height, 16*time.Millisecond)
+	w.Ticks = doc.Ticks
+	w.Year = doc.Year
+	w.Epoch = doc.Epoch
+	w.Trend = doc.Trend
+	w.ObjectsIdCounter = doc.ObjectsIdCounter
+	if doc.Config != nil {
+		w.Config = *doc.Config
+	}
+
+	if doc.Fields != nil {
+		if err := applyFields(w, doc.Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	for n, c := range doc.Cells {
+		cdesc, err := c.descriptor()
+		if err != nil {
+			return nil, fmt.Errorf("cell %d: %w", n, err)
+		}
+
+		if cdesc.Id == 0 {
+			return nil, fmt.Errorf("cell %d: id 0 is not valid", n)
+		}
+		if _, err := placeCell(w, cdesc); err != nil {
+			return nil, fmt.Errorf("cell %d: %w", n, err)
+		}
+		// new objects must not reuse the ids of the loaded ones, the counter is the last id taken
+		if cdesc.Id > w.ObjectsIdCounter {
+			w.ObjectsIdCounter = cdesc.Id
+		}
+	}
+
+	return w, nil
+}
+
+func (c JSONCell) descriptor() (wCellDescriptor, error) {
+	genome, err := c.Genome.genome()
+	if err != nil {
+		return wCellDescriptor{}, err
+	}
+	if c.Brain.CommandCounter >= cell.GenomeLength {
+		return wCellDescriptor{}, fmt.Errorf("command counter %d is out of the genome", c.Brain.CommandCounter)
+	}
+	if c.Brain.StackCounter > cell.StackDepth {
+		return wCellDescriptor{}, fmt.Errorf("stack counter %d is deeper than %d", c.Brain.StackCounter, cell.StackDepth)
+	}
+	for _, s := range c.Brain.Stack {
+		if s.JumpPosition >= cell.GenomeLength {
+			return wCellDescriptor{}, fmt.Errorf("stack jump position %d is out of the genome", s.JumpPosition)
+		}
+	}
+	for _, s := range c.Brain.Sensors {
+		if s.JumpPosition >= cell.GenomeLength {
+			return wCellDescriptor{}, fmt.Errorf("sensor jump position %d is out of the genome", s.JumpPosition)
+		}
+	}
+
+	return wCellDescriptor{
+		Id:             c.Id,
+		Generation:     c.Generation,
+		ParentsChain:   c.ParentsChain,
+		Age:            c.Age,
+		Health:         c.Health,
+		Energy:         c.Energy,
+		Weight:         c.Weight,
+		Died:           c.Died,
+		Picked:         c.Picked,
+		Genome:         genome,
+		Brain:          c.Brain,
+		Bagage:         c.Bagage,
+		BagageSelected: c.BagageSelected,
+		BagageFullness: c.BagageFullness,
+		Bonds:          c.Bonds,
+		Position:       c.Position,
+		Rotation:       c.Rotation,
+	}, nil
+}
+
+func (g JSONGenome) genome() (cell.Genome, error) {
+	if g.Code == "" {
+		if g.Disassembly == "" {
+			return cell.Genome{}, errors.New("genome has neither code nor disassembly")
+		}
+		genome, err := genasm.Assemble(g.Disassembly)
+		if err != nil {
+			return cell.Genome{}, fmt.Errorf("genome disassembly: %w", err)
+		}
+		return genome, nil
+	}
+
+	code, err := hex.DecodeString(g.Code)
+	if err != nil {
+		return cell.Genome{}, fmt.Errorf("genome code: %w", err)
+	}
+	if len(code) != cell.GenomeLength {
+		return cell.Genome{}, fmt.Errorf("genome code is %d bytes long instead of %d", len(code), cell.GenomeLength)
+	}
+
+	var commands [cell.GenomeLength]cell.Command
+	for i, b := range code {
+		commands[i] = cell.Command(b)
+	}
+	return cell.NewGenome(commands, g.Traits), nil
+}
+
+// Replace the default fields of the new world with the saved ones
+func applyFields(w *world.World, fields []JSONField) error {
+	for name := range w.Fields {
+		w.RemoveField(name)
+	}
+	for _, f := range fields {
+		field, err := f.environmentField()
+		if err != nil {
+			return fmt.Errorf("field %q: %w", f.Name, err)
+		}
+		w.AddField(f.Name, field)
+		if f.Paint != nil && !w.SetFieldPaint(f.Name, f.Paint) {
+			return fmt.Errorf("field %q: paint of %d squares doesn't cover the world", f.Name, len(f.Paint))
+		}
+	}
+	return nil
+}
+
+func (f JSONField) environmentField() (world.EnvironmentField, error) {
+	var field world.EnvironmentField
+	switch f.Kind {
+	case FIELD_KIND_LINEAR:
+		field = &world.LinearGradient{}
+	case FIELD_KIND_RADIAL:
+		field = &world.RadialHotspot{}
+	case FIELD_KIND_NOISE:
+		field = &world.NoiseField{}
+	case FIELD_KIND_IMAGE:
+		var params world.ImageField
+		if err := json.Unmarshal(f.Field, &params); err != nil {
+			return nil, err
+		}
+		image, err := world.NewImageField(params.Path, params.Intensity)
+		if err != nil {
+			return nil, err
+		}
+		image.OffsetX, image.DriftX = params.OffsetX, params.DriftX
+		return image, nil
+	default:
+		return nil, fmt.Errorf("unknown field kind %q", f.Kind)
+	}
+
+	if err := json.Unmarshal(f.Field, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+func jsonWorld(w *world.World) (*JSONWorld, error) {
+	config := w.Config
+	doc := &JSONWorld{
+		Width:            w.Width,
+		Height:           w.Height,
+		Ticks:            w.Ticks,
+		Year:             w.Year,
+		Epoch:            w.Epoch,
+		Trend:            w.Trend,
+		ObjectsIdCounter: w.ObjectsIdCounter,
+		Config:           &config,
+		Fields:           []JSONField{},
+	}
+
+	for _, name := range fieldNames(w) {
+		f, err := jsonField(w, name)
+		if err != nil {
+			return nil, err
+		}
+		doc.Fields = append(doc.Fields, f)
+	}
+	return doc, nil
+}
+
+func fieldNames(w *world.World) []string {
+	names := make([]string, 0, len(w.Fields))
+	for name := range w.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func jsonField(w *world.World, name string) (JSONField, error) {
+	f, err := fieldParams(w, name)
+	if err != nil {
+		return f, err
+	}
+
+	f.Values = make([]int, 0, w.Width*w.Height)
+	for y := int32(0); y < int32(w.Height); y++ {
+		for x := int32(0); x < int32(w.Width); x++ {
+			f.Values = append(f.Values, int(w.GetFieldAtPosition(name, object.Position{X: x, Y: y})))
+		}
+	}
+	f.Paint = w.GetFieldPaint(name)
+	return f, nil
+}
+
+// Kind and parameters of the field without the maps
+func fieldParams(w *world.World, name string) (JSONField, error) {
+	f := JSONField{Name: name}
+	switch w.Fields[name].(type) {
+	case *world.LinearGradient:
+		f.Kind = FIELD_KIND_LINEAR
+	case *world.RadialHotspot:
+		f.Kind = FIELD_KIND_RADIAL
+	case *world.NoiseField:
+		f.Kind = FIELD_KIND_NOISE
+	case *world.ImageField:
+		f.Kind = FIELD_KIND_IMAGE
+	default:
+		return f, fmt.Errorf("field %q of type %T can't be exported", name, w.Fields[name])
+	}
+
+	params, err := json.Marshal(w.Fields[name])
+	if err != nil {
+		return f, err
+	}
+	f.Field = params
+	return f, nil
+}
+
+func NewJSONGenome(g cell.Genome) JSONGenome {
+	code := make([]byte, len(g.Code))
+	g.Read(code)
+	return JSONGenome{
+		Hash:        g.Hash,
+		Traits:      g.Traits,
+		Code:        hex.EncodeToString(code),
+		Disassembly: genasm.Disassemble(g),
+	}
+}
+
+func NewJSONCell(c *cell.Cell) JSONCell {
+	return jsonCell(cellRecord(c, 0), c.Genome)
+}
+
+func jsonCell(record wCellRecord, genome cell.Genome) JSONCell {
+	return JSONCell{
+		Id:             record.Id,
+		Generation:     record.Generation,
+		ParentsChain:   record.ParentsChain,
+		Age:            record.Age,
+		Health:         record.Health,
+		Energy:         record.Energy,
+		Weight:         record.Weight,
+		Died:           record.Died,
+		Picked:         record.Picked,
+		Genome:         NewJSONGenome(genome),
+		Brain:          record.Brain,
+		Bagage:         record.Bagage,
+		BagageSelected: record.BagageSelected,
+		BagageFullness: record.BagageFullness,
+		Bonds:          record.Bonds,
+		Position:       record.Position,
+		Rotation:       record.Rotation,
+	}
+}
@@ -0,0 +1,151 @@
+package worldsaver
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/world"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      Format
+		compression Compression
+	}{
+		{"document", FORMAT_JSON, COMPRESSION_NONE},
+		{"lines", FORMAT_JSONL, COMPRESSION_NONE},
+		{"lines gzip", FORMAT_JSONL, COMPRESSION_GZIP},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := loadFixture(t, "world-v6.gdw")
+			// the JSON load calculates the hashes, the fixture ones are made up
+			for _, obj := range w.Objects {
+				c := obj.(*cell.Cell)
+				c.Genome = cell.NewGenome(c.Genome.Code, c.Genome.Traits)
+			}
+
+			var saved bytes.Buffer
+			if err := SaveAs(w, &saved, test.format, test.compression); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Load(&saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if loaded.Ticks != w.Ticks || loaded.Year != w.Year || loaded.ObjectsIdCounter != w.ObjectsIdCounter {
+				t.Errorf("tick %d, year %d, id counter %d", loaded.Ticks, loaded.Year, loaded.ObjectsIdCounter)
+			}
+			var want, got bytes.Buffer
+			if err := SaveJSON(w, &want, true); err != nil {
+				t.Fatal(err)
+			}
+			if err := SaveJSON(loaded, &got, true); err != nil {
+				t.Fatal(err)
+			}
+			if line := firstDiff(got.Bytes(), want.Bytes()); line != "" {
+				t.Errorf("loaded world differs from the saved one at %s", line)
+			}
+		})
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	defaults := world.DefaultConfig()
+	tests := []struct {
+		name string
+		// document fields after the size
+		doc string
+		err string
+		// expected caps and look range of the loaded world
+		maxEnergy, maxHealth uint32
+		lookRange            uint8
+	}{
+		{"missing config", ``, "",
+			defaults.MaxEnergy, defaults.MaxHealth, defaults.LookRange},
+		{"partial config", `, "Config": {"MaxEnergy": 1000, "LookRange": 3}`, "",
+			1000, defaults.MaxHealth, 3},
+		{"cells", `, "Cells": [` + testJSONCell(t, 1, 0, 0) + `,` + testJSONCell(t, 7, 3, 3) + `]`, "",
+			defaults.MaxEnergy, defaults.MaxHealth, defaults.LookRange},
+		{"zero id", `, "Cells": [` + testJSONCell(t, 0, 1, 1) + `]`, "id 0", 0, 0, 0},
+		{"duplicate id", `, "Cells": [` + testJSONCell(t, 2, 0, 0) + `,` + testJSONCell(t, 2, 1, 1) + `]`, "duplicate id", 0, 0, 0},
+		{"taken position", `, "Cells": [` + testJSONCell(t, 1, 2, 2) + `,` + testJSONCell(t, 2, 2, 2) + `]`, "is taken", 0, 0, 0},
+		{"out of the world", `, "Cells": [` + testJSONCell(t, 1, 4, 0) + `]`, "out of the world", 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, err := LoadJSON(strings.NewReader(`{"Width": 4, "Height": 4` + test.doc + `}`))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if w.Config.MaxEnergy != test.maxEnergy || w.Config.MaxHealth != test.maxHealth || w.Config.LookRange != test.lookRange {
+				t.Errorf("caps %d/%d, look range %d, want %d/%d, %d", w.Config.MaxEnergy, w.Config.MaxHealth, w.Config.LookRange,
+					test.maxEnergy, test.maxHealth, test.lookRange)
+			}
+			// new objects never reuse the loaded ids
+			for id := range w.Objects {
+				if id > w.ObjectsIdCounter {
+					t.Errorf("id counter %d is below the id %d", w.ObjectsIdCounter, id)
+				}
+			}
+		})
+	}
+}
+
+// The field maps are numbers, so the document stays readable
+func TestJSONFieldValues(t *testing.T) {
+	w := world.New(4, 3, time.Millisecond)
+	var saved bytes.Buffer
+	if err := SaveJSON(w, &saved, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Fields []struct {
+			Name   string
+			Values []int
+		}
+	}
+	if err := json.Unmarshal(saved.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Fields) == 0 {
+		t.Fatal("no fields saved")
+	}
+	for _, f := range doc.Fields {
+		if len(f.Values) != 4*3 {
+			t.Errorf("field %s has %d values, want 12", f.Name, len(f.Values))
+		}
+		for i, v := range f.Values {
+			if want := int(w.GetFieldAtPosition(f.Name, object.Position{X: int32(i % 4), Y: int32(i / 4)})); v != want {
+				t.Errorf("field %s value %d is %d, want %d", f.Name, i, v, want)
+			}
+		}
+	}
+}
+
+func testJSONCell(t *testing.T, id uint64, x, y int32) string {
+	w := world.New(8, 8, time.Millisecond)
+	c := cell.New(w, nil, object.Position{X: x, Y: y})
+	c.Name = id
+	data, err := json.Marshal(NewJSONCell(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
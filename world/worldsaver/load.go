@@ -1,7 +1,12 @@
 package worldsaver
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"gopher-dish/cell"
 	"gopher-dish/object"
@@ -10,19 +15,114 @@ import (
 	"time"
 )
 
-func Load(reader io.Reader) (w *world.World, err error) {
-	var desc wDescriptor
-	err = binary.Read(reader, binary.LittleEndian, &desc)
+const (
+	// Binary saves keep the world sides below it
+	MaxWorldSide = 1 << 16
+)
+
+var (
+	gzipMagic = []byte{0x1F, 0x8B}
+	zstdMagic = []byte{0x28, 0xB5, 0x2F, 0xFD}
+	utf8BOM   = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// Loads plain and gzip compressed saves of the binary and JSON formats
+func Load(r io.Reader) (w *world.World, err error) {
+	buffered := bufio.NewReader(r)
+	var reader io.Reader = buffered
+	if magic, _ := buffered.Peek(len(zstdMagic)); bytes.Equal(magic, zstdMagic) {
+		err = errors.New("zstd compressed saves are not supported, decompress the file first")
+		return
+	} else if bytes.HasPrefix(magic, gzipMagic) {
+		var zr *gzip.Reader
+		zr, err = gzip.NewReader(buffered)
+		if err != nil {
+			return
+		}
+		defer zr.Close()
+		buffered = bufio.NewReader(zr)
+		reader = buffered
+	}
+	if head, _ := buffered.Peek(8); !binaryHeader(head) && jsonStart(buffered) {
+		return LoadJSON(buffered)
+	}
+
+	var header wHeader
+	err = binary.Read(reader, binary.LittleEndian, &header.Magic)
+	if err != nil {
+		return
+	}
+
+	if header.Magic == saveMagic {
+		err = binary.Read(reader, binary.LittleEndian, &header.Version)
+		if err != nil {
+			return
+		}
+		if header.Version > saveVersion {
+			err = fmt.Errorf("unsupported save version %d", header.Version)
+			return
+		}
+	} else {
+		// Legacy file, the magic is the world width actually
+		magic := make([]byte, 4)
+		binary.LittleEndian.PutUint32(magic, header.Magic)
+		reader = io.MultiReader(bytes.NewReader(magic), reader)
+	}
+
+	desc, err := readDescriptor(reader, header.Version)
 	if err != nil {
 		return
 	}
+	if desc.Width == 0 || desc.Height == 0 || desc.Width >= MaxWorldSide || desc.Height >= MaxWorldSide {
+		err = fmt.Errorf("world size %dx%d is out of 1-%d", desc.Width, desc.Height, MaxWorldSide-1)
+		return
+	}
+
+	var genomes []cell.Genome
+	if header.Version >= 4 {
+		var count uint64
+		err = binary.Read(reader, binary.LittleEndian, &count)
+		if err != nil {
+			return
+		}
+		// every genome belongs to a cell, so a damaged count can't allocate more than the objects
+		if count > desc.ObjectCount {
+			err = fmt.Errorf("genomes count %d is over the objects count %d", count, desc.ObjectCount)
+			return
+		}
+		genomes = make([]cell.Genome, count)
+		for i := range genomes {
+			err = binary.Read(reader, binary.LittleEndian, &genomes[i])
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	var fields []JSONField
+	if header.Version >= 6 {
+		fields, err = readFields(reader, desc)
+		if err != nil {
+			return
+		}
+	}
 
 	w = world.New(desc.Width, desc.Height, 16*time.Millisecond)
+	if fields != nil {
+		if err = applyFields(w, fields); err != nil {
+			w = nil
+			return
+		}
+	}
 
 	w.Ticks = desc.Ticks
 	w.Year = desc.Year
 	w.Epoch = desc.Epoch
 	w.ObjectsIdCounter = desc.ObjectIdCount
+	if desc.MaxEnergy != 0 && desc.MaxHealth != 0 {
+		w.Config.MaxEnergy = desc.MaxEnergy
+		w.Config.MaxHealth = desc.MaxHealth
+	}
 
 	for i := 0; i < int(desc.ObjectCount); i++ {
 		var otype uint64
@@ -36,30 +136,15 @@ func Load(reader io.Reader) (w *world.World, err error) {
 		switch otype {
 		case object.TYPE_CELL:
 			var cdesc wCellDescriptor
-			err = binary.Read(reader, binary.LittleEndian, &cdesc)
+			cdesc, err = readCellDescriptor(reader, header.Version, genomes)
 			if err != nil {
 				return
 			}
 
-			c := &cell.Cell{
-				Name:         cdesc.Id,
-				Generation:   cdesc.Generation,
-				ParentsChain: cdesc.ParentsChain,
-				Age:          cdesc.Age,
-				Health:       cdesc.Health,
-				Energy:       cdesc.Energy,
-				Weight:       cdesc.Weight,
-				Died:         cdesc.Died,
-				Picked:       cdesc.Picked,
-				Genome:       cdesc.Genome,
-				Brain:        cdesc.Brain,
-				Position:     cdesc.Position,
-				Rotation:     cdesc.Rotation,
-				World:        w,
+			if _, err = placeCell(w, cdesc); err != nil {
+				err = fmt.Errorf("cell %d: %w", cdesc.Id, err)
+				return
 			}
-
-			w.Objects[cdesc.Id] = c
-			w.Places[cdesc.Position.X][cdesc.Position.Y] = c
 		default:
 			err = fmt.Errorf("unknown object type 0x%X", otype)
 			return
@@ -69,6 +154,10 @@ func Load(reader io.Reader) (w *world.World, err error) {
 
 	// fmt.Printf("load: %d/%d\r\n", int(desc.ObjectCount), int(desc.ObjectCount))
 
+	if header.Version < 5 {
+		warnOverCaps(w)
+	}
+
 	/*
 		TODO: import cells bagage
 		iterate all cells and assign bagage items by ID
@@ -76,3 +165,166 @@ func Load(reader io.Reader) (w *world.World, err error) {
 
 	return
 }
+
+// Binary saves start with the magic, legacy ones with the width and the height.
+// Their high bytes are zero for any sane world, while JSON text has no zero bytes
+func binaryHeader(head []byte) bool {
+	if len(head) < 8 {
+		return false
+	}
+	if binary.LittleEndian.Uint32(head) == saveMagic {
+		return true
+	}
+	width, height := binary.LittleEndian.Uint32(head), binary.LittleEndian.Uint32(head[4:])
+	return width > 0 && width < MaxWorldSide && height > 0 && height < MaxWorldSide
+}
+
+// Skip the byte order mark and the whitespace before the JSON document
+func jsonStart(buffered *bufio.Reader) bool {
+	if bom, _ := buffered.Peek(len(utf8BOM)); bytes.Equal(bom, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+	for {
+		b, err := buffered.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		buffered.UnreadByte()
+		return b == '{'
+	}
+}
+
+func readDescriptor(reader io.Reader, version uint32) (desc wDescriptor, err error) {
+	if version >= 5 {
+		err = binary.Read(reader, binary.LittleEndian, &desc)
+		return
+	}
+	var legacy wDescriptorV4
+	err = binary.Read(reader, binary.LittleEndian, &legacy)
+	return legacy.upgrade(), err
+}
+
+// Files saved before the caps don't know them, the cells over the default caps
+// would be clamped silently by the first tick
+func warnOverCaps(w *world.World) {
+	over := 0
+	for _, obj := range w.Objects {
+		if c, ok := obj.(*cell.Cell); ok && (c.Energy > w.Config.MaxEnergy || c.Health > w.Config.MaxHealth) {
+			over++
+		}
+	}
+	if over > 0 {
+		fmt.Printf("Warning: %d cells exceed the energy cap %d or the health cap %d, the world was saved with other caps\n",
+			over, w.Config.MaxEnergy, w.Config.MaxHealth)
+	}
+}
+
+// Damaged saves may hold positions out of the world or taken twice
+func placeCell(w *world.World, cdesc wCellDescriptor) (*cell.Cell, error) {
+	pos := cdesc.Position
+	if pos.X < 0 || pos.X >= int32(w.Width) || pos.Y < 0 || pos.Y >= int32(w.Height) {
+		return nil, fmt.Errorf("position [%d, %d] is out of the world", pos.X, pos.Y)
+	}
+	if other := w.Places[pos.X][pos.Y]; other != nil {
+		return nil, fmt.Errorf("position [%d, %d] is taken by object %d", pos.X, pos.Y, other.GetID())
+	}
+	if _, exists := w.Objects[cdesc.Id]; exists {
+		return nil, fmt.Errorf("duplicate id %d", cdesc.Id)
+	}
+
+	c := &cell.Cell{
+		Name:         cdesc.Id,
+		Generation:   cdesc.Generation,
+		ParentsChain: cdesc.ParentsChain,
+		Age:          cdesc.Age,
+		Health:       cdesc.Health,
+		Energy:       cdesc.Energy,
+		Weight:       cdesc.Weight,
+		Died:         cdesc.Died,
+		Picked:       cdesc.Picked,
+		Genome:       cdesc.Genome,
+		Brain:        cdesc.Brain,
+		Bonds:        cdesc.Bonds,
+		Position:     cdesc.Position,
+		Rotation:     cdesc.Rotation,
+		World:        w,
+	}
+
+	w.Objects[cdesc.Id] = c
+	w.Places[cdesc.Position.X][cdesc.Position.Y] = c
+	return c, nil
+}
+
+func readFields(reader io.Reader, desc wDescriptor) ([]JSONField, error) {
+	var count uint64
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	if count > maxFields {
+		return nil, fmt.Errorf("fields count %d is over %d", count, maxFields)
+	}
+
+	fields := make([]JSONField, count)
+	for i := range fields {
+		var size uint32
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+		if size > maxFieldParamsSize {
+			return nil, fmt.Errorf("field %d parameters of %d bytes are over %d", i, size, maxFieldParamsSize)
+		}
+		params := make([]byte, size)
+		if _, err := io.ReadFull(reader, params); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(params, &fields[i]); err != nil {
+			return nil, fmt.Errorf("field %d: %w", i, err)
+		}
+
+		var paintSize uint64
+		if err := binary.Read(reader, binary.LittleEndian, &paintSize); err != nil {
+			return nil, err
+		}
+		if paintSize == 0 {
+			continue
+		}
+		if paintSize != uint64(desc.Width)*uint64(desc.Height) {
+			return nil, fmt.Errorf("field %q paint of %d squares doesn't cover the world", fields[i].Name, paintSize)
+		}
+		fields[i].Paint = make([]float32, paintSize)
+		if err := binary.Read(reader, binary.LittleEndian, fields[i].Paint); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+func readCellDescriptor(reader io.Reader, version uint32, genomes []cell.Genome) (cdesc wCellDescriptor, err error) {
+	switch version {
+	case 0:
+		var legacy wCellDescriptorV0
+		err = binary.Read(reader, binary.LittleEndian, &legacy)
+		cdesc = legacy.upgrade().upgrade().upgrade()
+	case 1:
+		var legacy wCellDescriptorV1
+		err = binary.Read(reader, binary.LittleEndian, &legacy)
+		cdesc = legacy.upgrade().upgrade()
+	case 2:
+		var legacy wCellDescriptorV2
+		err = binary.Read(reader, binary.LittleEndian, &legacy)
+		cdesc = legacy.upgrade()
+	case 3:
+		err = binary.Read(reader, binary.LittleEndian, &cdesc)
+	default:
+		var record wCellRecord
+		err = binary.Read(reader, binary.LittleEndian, &record)
+		if err == nil {
+			cdesc, err = record.resolve(genomes)
+		}
+	}
+	return
+}
@@ -0,0 +1,325 @@
+package worldsaver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"gopher-dish/cell"
+	"gopher-dish/object"
+	"gopher-dish/world"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fixtures are written by the save code of every version. The world is 12x10 at tick 1234
+// with three cells: cell 1 and cell 3 share genome 0xABCD and are bonded since version 1,
+// dead cell 2 has genome 0xBEEF with the bite trait 99 since version 2.
+// Cell 1 energy is over the default cap since version 3. The sunlight has drifted,
+// the heat hotspot is added and the minerals are painted since version 6
+func TestLoadVersions(t *testing.T) {
+	base := cell.BaseTraits()
+	tests := []struct {
+		file                 string
+		bonded               bool
+		bite                 byte
+		energy               uint32
+		maxEnergy, maxHealth uint32
+		fields               bool
+	}{
+		{"world-v0.gdw", false, base[cell.TRAIT_BITE], 150, world.WorldMaxEnergy, world.WorldMaxHealth, false},
+		{"world-v1.gdw", true, base[cell.TRAIT_BITE], 150, world.WorldMaxEnergy, world.WorldMaxHealth, false},
+		{"world-v2.gdw", true, 99, 150, world.WorldMaxEnergy, world.WorldMaxHealth, false},
+		{"world-v3.gdw", true, 99, 1000, world.WorldMaxEnergy, world.WorldMaxHealth, false},
+		{"world-v4.gdw", true, 99, 1000, world.WorldMaxEnergy, world.WorldMaxHealth, false},
+		{"world-v5.gdw", true, 99, 1000, 2000, 800, false},
+		{"world-v6.gdw", true, 99, 1000, 2000, 800, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			w := loadFixture(t, test.file)
+
+			if w.Width != 12 || w.Height != 10 || w.Ticks != 1234 || w.Year != 12 || w.Epoch != 1 {
+				t.Errorf("world %dx%d at tick %d year %d epoch %d", w.Width, w.Height, w.Ticks, w.Year, w.Epoch)
+			}
+			if len(w.Objects) != 3 || w.ObjectsIdCounter != 3 {
+				t.Errorf("%d objects, id counter %d", len(w.Objects), w.ObjectsIdCounter)
+			}
+			if w.Config.MaxEnergy != test.maxEnergy || w.Config.MaxHealth != test.maxHealth {
+				t.Errorf("caps %d/%d, want %d/%d", w.Config.MaxEnergy, w.Config.MaxHealth, test.maxEnergy, test.maxHealth)
+			}
+
+			c1, c2, c3 := fixtureCell(t, w, 1), fixtureCell(t, w, 2), fixtureCell(t, w, 3)
+			checkPlaced(t, w, c1, object.Position{X: 2, Y: 3})
+			checkPlaced(t, w, c2, object.Position{X: 5, Y: 6})
+			checkPlaced(t, w, c3, object.Position{X: 7, Y: 1})
+
+			if c1.Age != 7 || c1.Generation != 3 || c1.Health != 90 || c1.Weight != 5 || c1.ParentsChain[0] != 42 {
+				t.Errorf("cell 1 age %d, generation %d, health %d, weight %d, parent %d",
+					c1.Age, c1.Generation, c1.Health, c1.Weight, c1.ParentsChain[0])
+			}
+			if c1.Energy != test.energy || c2.Energy != 40 || c3.Energy != 60 {
+				t.Errorf("energy %d, %d, %d, want %d, 40, 60", c1.Energy, c2.Energy, c3.Energy, test.energy)
+			}
+			if !c2.Died || c2.Health != 20 || c1.Died || c3.Died {
+				t.Errorf("died %v, %v, %v, cell 2 health %d", c1.Died, c2.Died, c3.Died, c2.Health)
+			}
+			if c3.Rotation.Degree != 90 {
+				t.Errorf("cell 3 rotation %d, want 90", c3.Rotation.Degree)
+			}
+
+			if c1.Genome.Hash != 0xABCD || c1.Genome != c3.Genome {
+				t.Errorf("cells 1 and 3 genomes %#x and %#x", c1.Genome.Hash, c3.Genome.Hash)
+			}
+			if c1.Genome.Traits != base {
+				t.Errorf("cell 1 traits %v, want %v", c1.Genome.Traits, base)
+			}
+			// both genomes are the base genome of the version, the second one starts with recycle
+			code := c1.Genome.Code
+			code[0] = cell.CMD_RECYCLE
+			if c2.Genome.Hash != 0xBEEF || c2.Genome.Code != code {
+				t.Errorf("cell 2 genome %#x starts with %d", c2.Genome.Hash, c2.Genome.Code[0])
+			}
+			if c2.Genome.Traits[cell.TRAIT_BITE] != test.bite {
+				t.Errorf("cell 2 bite %d, want %d", c2.Genome.Traits[cell.TRAIT_BITE], test.bite)
+			}
+
+			var bond1, bond3 uint64
+			if test.bonded {
+				bond1, bond3 = 3, 1
+			}
+			if c1.Bonds[0] != bond1 || c3.Bonds[0] != bond3 {
+				t.Errorf("bonds %d and %d, want %d and %d", c1.Bonds[0], c3.Bonds[0], bond1, bond3)
+			}
+
+			checkFields(t, w, test.fields)
+		})
+	}
+}
+
+// The format is detected by the content, JSON may start with the whitespace or the byte order mark
+func TestLoadFormats(t *testing.T) {
+	legacy, err := os.ReadFile(filepath.Join("testdata", "world-v0.gdw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc bytes.Buffer
+	if err := SaveJSON(loadFixture(t, "world-v5.gdw"), &doc, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		data  func() []byte
+		width uint32
+		err   string
+	}{
+		// the first width byte is '{'
+		{"legacy 379 wide", func() []byte {
+			data := append([]byte(nil), legacy...)
+			binary.LittleEndian.PutUint32(data, 379)
+			return data
+		}, 379, ""},
+		{"json", doc.Bytes, 12, ""},
+		{"json after whitespace", func() []byte {
+			return append([]byte(" \r\n\t"), doc.Bytes()...)
+		}, 12, ""},
+		{"json after byte order mark", func() []byte {
+			return append([]byte("\xEF\xBB\xBF\n"), doc.Bytes()...)
+		}, 12, ""},
+		{"short json", func() []byte {
+			return []byte(`{"Width":3,"Height":2}`)
+		}, 3, ""},
+		{"text", func() []byte {
+			return []byte("  not a save")
+		}, 0, "EOF"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, err := Load(bytes.NewReader(test.data()))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Width != test.width {
+				t.Errorf("width %d, want %d", w.Width, test.width)
+			}
+		})
+	}
+}
+
+func TestLoadDamaged(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "world-v5.gdw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerSize := binary.Size(wHeader{})
+	countOffset := headerSize + binary.Size(wDescriptor{})
+
+	tests := []struct {
+		name   string
+		damage func(data []byte) []byte
+		err    string
+	}{
+		{"newer version", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[4:], saveVersion+1)
+			return data
+		}, "unsupported save version"},
+		{"genomes over the objects", func(data []byte) []byte {
+			binary.LittleEndian.PutUint64(data[countOffset:], 1<<40)
+			return data
+		}, "genomes count"},
+		{"truncated", func(data []byte) []byte {
+			return data[:len(data)-10]
+		}, "EOF"},
+		{"zero width", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[headerSize:], 0)
+			return data
+		}, "world size 0x10"},
+		{"position out of the world", func(data []byte) []byte {
+			return damageCell(t, data, 2, func(r *wCellRecord) { r.Position.X = 15 })
+		}, "position [15, 6] is out of the world"},
+		{"negative position", func(data []byte) []byte {
+			return damageCell(t, data, 1, func(r *wCellRecord) { r.Position.Y = -1 })
+		}, "is out of the world"},
+		{"taken position", func(data []byte) []byte {
+			return damageCell(t, data, 3, func(r *wCellRecord) { r.Position = object.Position{X: 2, Y: 3} })
+		}, "is taken by object"},
+		{"duplicate id", func(data []byte) []byte {
+			return damageCell(t, data, 3, func(r *wCellRecord) { r.Id = 1 })
+		}, "duplicate id 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			damaged := test.damage(append([]byte(nil), data...))
+			_, err := Load(bytes.NewReader(damaged))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+// The config file of the command line is applied over the loaded world
+func TestLoadWithConfig(t *testing.T) {
+	tests := []struct {
+		config               string
+		maxEnergy, maxHealth uint32
+		lookRange            uint8
+	}{
+		{`{}`, 2000, 800, world.WorldLookRange},
+		{`{"LookRange": 3, "StatsInterval": 10}`, 2000, 800, 3},
+		{`{"MaxEnergy": 500}`, 500, 800, world.WorldLookRange},
+	}
+
+	for _, test := range tests {
+		t.Run(test.config, func(t *testing.T) {
+			w := loadFixture(t, "world-v5.gdw")
+			if err := w.Config.Apply(strings.NewReader(test.config)); err != nil {
+				t.Fatal(err)
+			}
+			if w.Config.MaxEnergy != test.maxEnergy || w.Config.MaxHealth != test.maxHealth || w.Config.LookRange != test.lookRange {
+				t.Errorf("caps %d/%d, look range %d, want %d/%d, %d", w.Config.MaxEnergy, w.Config.MaxHealth, w.Config.LookRange,
+					test.maxEnergy, test.maxHealth, test.lookRange)
+			}
+		})
+	}
+}
+
+// Change the cell record of the version 5 save
+func damageCell(t *testing.T, data []byte, id uint64, damage func(r *wCellRecord)) []byte {
+	t.Helper()
+	reader := bytes.NewReader(data)
+	reader.Seek(int64(binary.Size(wHeader{})+binary.Size(wDescriptor{})), io.SeekStart)
+	var count uint64
+	binary.Read(reader, binary.LittleEndian, &count)
+	reader.Seek(int64(count)*int64(binary.Size(cell.Genome{})), io.SeekCurrent)
+
+	for reader.Len() > 0 {
+		var otype uint64
+		var record wCellRecord
+		binary.Read(reader, binary.LittleEndian, &otype)
+		offset := len(data) - reader.Len()
+		if err := binary.Read(reader, binary.LittleEndian, &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Id != id {
+			continue
+		}
+
+		damage(&record)
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, &record)
+		copy(data[offset:], buf.Bytes())
+		return data
+	}
+	t.Fatalf("there is no cell %d", id)
+	return nil
+}
+
+func checkFields(t *testing.T, w *world.World, changed bool) {
+	t.Helper()
+	defaults := world.New(w.Width, w.Height, time.Millisecond)
+	sunlight := *w.GetField(world.FIELD_SUNLIGHT).(*world.LinearGradient)
+	defaultSunlight := *defaults.GetField(world.FIELD_SUNLIGHT).(*world.LinearGradient)
+	_, heat := w.GetField("heat").(*world.RadialHotspot)
+	painted := w.GetFieldPaint(world.FIELD_MINERALS) != nil
+
+	if !changed {
+		if sunlight != defaultSunlight || len(w.Fields) != len(defaults.Fields) || painted {
+			t.Errorf("sunlight %+v, %d fields, painted %v, want the default fields", sunlight, len(w.Fields), painted)
+		}
+		return
+	}
+	if sunlight.BeginDrift != 0.01 || sunlight.Begin != defaultSunlight.Begin+0.01 {
+		t.Errorf("sunlight %+v has not drifted from %+v", sunlight, defaultSunlight)
+	}
+	if !heat || !painted {
+		t.Errorf("heat field %v, minerals painted %v", heat, painted)
+	}
+	square := object.Position{X: 4, Y: 4}
+	if got, want := w.GetFieldAtPosition(world.FIELD_MINERALS, square), defaults.GetFieldAtPosition(world.FIELD_MINERALS, square); got != want+30 {
+		t.Errorf("painted minerals %d, want %d", got, want+30)
+	}
+}
+
+func loadFixture(t *testing.T, name string) *world.World {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w, err := Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func fixtureCell(t *testing.T, w *world.World, id uint64) *cell.Cell {
+	t.Helper()
+	c, ok := w.Objects[id].(*cell.Cell)
+	if !ok {
+		t.Fatalf("there is no cell %d", id)
+	}
+	return c
+}
+
+func checkPlaced(t *testing.T, w *world.World, c *cell.Cell, pos object.Position) {
+	t.Helper()
+	if c.Position != pos || w.Places[pos.X][pos.Y] != c {
+		t.Errorf("cell %d at [%d, %d], want [%d, %d]", c.Name, c.Position.X, c.Position.Y, pos.X, pos.Y)
+	}
+}
@@ -1,13 +1,93 @@
 package worldsaver
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/binary"
+	"encoding/json"
+	"gopher-dish/cell"
+	"gopher-dish/object"
 	"gopher-dish/world"
 	"io"
+	"path/filepath"
+	"strings"
 )
 
-func Save(w *world.World, writer io.Writer) (err error) {
+type Compression int
+
+// Save compression list, the compression is detected on load
+const (
+	COMPRESSION_NONE Compression = iota
+	COMPRESSION_GZIP
+)
+
+type Format byte
+
+// Save formats list
+const (
+	FORMAT_BINARY Format = iota
+	FORMAT_JSON
+	FORMAT_JSONL
+)
+
+const (
+	JSONExtension      = ".json"
+	JSONLinesExtension = ".jsonl"
+	GzipExtension      = ".gz"
+)
+
+// Files with the ".gz" extension are compressed
+func CompressionByPath(path string) Compression {
+	if strings.HasSuffix(path, GzipExtension) {
+		return COMPRESSION_GZIP
+	}
+	return COMPRESSION_NONE
+}
+
+// Format of the file extension before the compression one, binary is the default
+func FormatByPath(path string) Format {
+	switch filepath.Ext(strings.TrimSuffix(path, GzipExtension)) {
+	case JSONExtension:
+		return FORMAT_JSON
+	case JSONLinesExtension:
+		return FORMAT_JSONL
+	}
+	return FORMAT_BINARY
+}
+
+func Save(w *world.World, writer io.Writer) error {
+	return SaveAs(w, writer, FORMAT_BINARY, COMPRESSION_NONE)
+}
+
+// Save the stopped or locked world, the objects are encoded straight to the writer
+func SaveAs(w *world.World, writer io.Writer, format Format, compression Compression) error {
+	if compression == COMPRESSION_GZIP {
+		zw, err := gzip.NewWriterLevel(writer, gzip.BestSpeed)
+		if err != nil {
+			return err
+		}
+		if err = save(w, zw, format); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+	return save(w, writer, format)
+}
+
+func save(w *world.World, writer io.Writer, format Format) error {
+	switch format {
+	case FORMAT_JSON:
+		return SaveJSON(w, writer, false)
+	case FORMAT_JSONL:
+		return SaveJSON(w, writer, true)
+	}
+	return saveBinary(w, writer)
+}
+
+// Every distinct genome is stored once before the fields and the objects
+func saveBinary(w *world.World, writer io.Writer) error {
+	genomes, index := genomeTable(w)
 	desc := wDescriptor{
 		Width:         w.Width,
 		Height:        w.Height,
@@ -16,15 +96,118 @@ func Save(w *world.World, writer io.Writer) (err error) {
 		Epoch:         w.Epoch,
 		ObjectCount:   uint64(len(w.Objects)),
 		ObjectIdCount: w.ObjectsIdCounter,
+		MaxEnergy:     w.Config.MaxEnergy,
+		MaxHealth:     w.Config.MaxHealth,
 	}
 
-	buf := new(bytes.Buffer)
+	buf := bufio.NewWriter(writer)
+	binary.Write(buf, binary.LittleEndian, wHeader{Magic: saveMagic, Version: saveVersion})
 	binary.Write(buf, binary.LittleEndian, desc)
+	binary.Write(buf, binary.LittleEndian, uint64(len(genomes)))
+	for i := range genomes {
+		if err := binary.Write(buf, binary.LittleEndian, &genomes[i]); err != nil {
+			return err
+		}
+	}
+
+	names := fieldNames(w)
+	binary.Write(buf, binary.LittleEndian, uint64(len(names)))
+	for _, name := range names {
+		if err := writeField(buf, w, name); err != nil {
+			return err
+		}
+	}
 
 	for _, obj := range w.Objects {
-		obj.Save(buf)
+		c, ok := obj.(*cell.Cell)
+		if !ok {
+			if err := obj.Save(buf); err != nil {
+				return err
+			}
+			continue
+		}
+
+		record := cellRecord(c, index[c.Genome])
+		binary.Write(buf, binary.LittleEndian, uint64(object.TYPE_CELL))
+		if err := binary.Write(buf, binary.LittleEndian, &record); err != nil {
+			return err
+		}
 	}
 
-	_, err = buf.WriteTo(writer)
-	return
+	return buf.Flush()
+}
+
+// Field parameters are stored as in JSON, the paint as float32 squares column by column
+func writeField(writer io.Writer, w *world.World, name string) error {
+	f, err := fieldParams(w, name)
+	if err != nil {
+		return err
+	}
+	params, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	binary.Write(writer, binary.LittleEndian, uint32(len(params)))
+	writer.Write(params)
+	paint := w.GetFieldPaint(name)
+	binary.Write(writer, binary.LittleEndian, uint64(len(paint)))
+	return binary.Write(writer, binary.LittleEndian, paint)
+}
+
+// Distinct genomes of the cells and their indexes
+func genomeTable(w *world.World) ([]cell.Genome, map[cell.Genome]uint32) {
+	var genomes []cell.Genome
+	index := make(map[cell.Genome]uint32)
+	for _, obj := range w.Objects {
+		c, ok := obj.(*cell.Cell)
+		if !ok {
+			continue
+		}
+		if _, ok := index[c.Genome]; !ok {
+			index[c.Genome] = uint32(len(genomes))
+			genomes = append(genomes, c.Genome)
+		}
+	}
+	return genomes, index
+}
+
+// Binary save of the world taken under the world lock, so the autosaver
+// compresses and writes it without holding the world
+func snapshot(w *world.World) ([]byte, error) {
+	w.PlacesDrawMux.Lock()
+	defer w.PlacesDrawMux.Unlock()
+
+	var buf bytes.Buffer
+	if err := saveBinary(w, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func cellRecord(c *cell.Cell, genome uint32) wCellRecord {
+	record := wCellRecord{
+		Id:             c.Name,
+		Generation:     c.Generation,
+		ParentsChain:   c.ParentsChain,
+		Age:            c.Age,
+		Health:         c.Health,
+		Energy:         c.Energy,
+		Weight:         c.Weight,
+		Died:           c.Died,
+		Picked:         c.Picked,
+		Genome:         genome,
+		Brain:          c.Brain,
+		BagageSelected: c.BagageSelected,
+		BagageFullness: c.BagageFullness,
+		Bonds:          c.Bonds,
+		Position:       c.Position,
+		Rotation:       c.Rotation,
+	}
+	for i, bagage := range c.Bagage {
+		if bagage != nil {
+			record.Bagage[i] = bagage.GetID()
+		}
+	}
+	return record
 }
@@ -0,0 +1,89 @@
+package worldsaver
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestSaveRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      Format
+		compression Compression
+	}{
+		{"binary", FORMAT_BINARY, COMPRESSION_NONE},
+		{"binary gzip", FORMAT_BINARY, COMPRESSION_GZIP},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := loadFixture(t, "world-v6.gdw")
+
+			var saved bytes.Buffer
+			if err := SaveAs(w, &saved, test.format, test.compression); err != nil {
+				t.Fatal(err)
+			}
+			if gzipped := bytes.HasPrefix(saved.Bytes(), gzipMagic); gzipped != (test.compression == COMPRESSION_GZIP) {
+				t.Errorf("gzip magic %v", gzipped)
+			}
+
+			loaded, err := Load(&saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Config.MaxEnergy != w.Config.MaxEnergy || loaded.Config.MaxHealth != w.Config.MaxHealth {
+				t.Errorf("caps %d/%d, want %d/%d", loaded.Config.MaxEnergy, loaded.Config.MaxHealth, w.Config.MaxEnergy, w.Config.MaxHealth)
+			}
+
+			// the JSON Lines are sorted by the cell id, so equal worlds give equal lines
+			var want, got bytes.Buffer
+			if err := SaveJSON(w, &want, true); err != nil {
+				t.Fatal(err)
+			}
+			if err := SaveJSON(loaded, &got, true); err != nil {
+				t.Fatal(err)
+			}
+			if line := firstDiff(got.Bytes(), want.Bytes()); line != "" {
+				t.Errorf("loaded world differs from the saved one at %s", line)
+			}
+		})
+	}
+}
+
+// First different line with the position of the difference, the lines are long
+func firstDiff(got, want []byte) string {
+	gotLines, wantLines := bytes.Split(got, []byte("\n")), bytes.Split(want, []byte("\n"))
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w []byte
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if bytes.Equal(g, w) {
+			continue
+		}
+		at := 0
+		for at < len(g) && at < len(w) && g[at] == w[at] {
+			at++
+		}
+		return fmt.Sprintf("line %d: %q, want %q", i+1, excerpt(g, at), excerpt(w, at))
+	}
+	return ""
+}
+
+func excerpt(line []byte, at int) string {
+	from, to := at-40, at+40
+	if from < 0 {
+		from = 0
+	}
+	if to > len(line) {
+		to = len(line)
+	}
+	if from > to {
+		return ""
+	}
+	return string(line[from:to])
+}
@@ -1,10 +1,27 @@
 package worldsaver
 
 import (
+	"fmt"
 	"gopher-dish/cell"
 	"gopher-dish/object"
+	"gopher-dish/world"
 )
 
+const (
+	// "GDW\0", legacy files start with the world width instead
+	saveMagic   = 0x00574447
+	saveVersion = 6
+
+	// Bounds of the field section against damaged files
+	maxFields          = 256
+	maxFieldParamsSize = 1 << 16
+)
+
+type wHeader struct {
+	Magic   uint32
+	Version uint32
+}
+
 type wDescriptor struct {
 	Width, Height uint32
 
@@ -14,6 +31,35 @@ type wDescriptor struct {
 
 	ObjectCount   uint64
 	ObjectIdCount uint64
+
+	// Energy and health caps of the config
+	MaxEnergy, MaxHealth uint32
+}
+
+// World descriptor of the files saved before the caps
+type wDescriptorV4 struct {
+	Width, Height uint32
+
+	Ticks uint64
+	Year  uint64
+	Epoch uint64
+
+	ObjectCount   uint64
+	ObjectIdCount uint64
+}
+
+func (d wDescriptorV4) upgrade() wDescriptor {
+	return wDescriptor{
+		Width:         d.Width,
+		Height:        d.Height,
+		Ticks:         d.Ticks,
+		Year:          d.Year,
+		Epoch:         d.Epoch,
+		ObjectCount:   d.ObjectCount,
+		ObjectIdCount: d.ObjectIdCount,
+		MaxEnergy:     world.WorldMaxEnergy,
+		MaxHealth:     world.WorldMaxHealth,
+	}
 }
 
 type wCellDescriptor struct {
@@ -21,6 +67,85 @@ type wCellDescriptor struct {
 	Generation   uint64
 	ParentsChain object.ParentsChain
 
+	Age    uint32
+	Health uint32
+	Energy uint32
+	Weight byte
+
+	Died   bool
+	Picked bool
+
+	Genome cell.Genome
+	Brain  cell.Brain
+
+	Bagage         [cell.BagageSize]uint64
+	BagageSelected uint32
+	BagageFullness uint32
+
+	Bonds [cell.BondsCount]uint64
+
+	Position object.Position
+	Rotation object.Rotation
+}
+
+// Cell of the file, the genome is the index in the genomes table written before the cells
+type wCellRecord struct {
+	Id           uint64
+	Generation   uint64
+	ParentsChain object.ParentsChain
+
+	Age    uint32
+	Health uint32
+	Energy uint32
+	Weight byte
+
+	Died   bool
+	Picked bool
+
+	Genome uint32
+	Brain  cell.Brain
+
+	Bagage         [cell.BagageSize]uint64
+	BagageSelected uint32
+	BagageFullness uint32
+
+	Bonds [cell.BondsCount]uint64
+
+	Position object.Position
+	Rotation object.Rotation
+}
+
+func (r wCellRecord) resolve(genomes []cell.Genome) (wCellDescriptor, error) {
+	if int(r.Genome) >= len(genomes) {
+		return wCellDescriptor{}, fmt.Errorf("cell %d refers to missing genome %d", r.Id, r.Genome)
+	}
+	return wCellDescriptor{
+		Id:             r.Id,
+		Generation:     r.Generation,
+		ParentsChain:   r.ParentsChain,
+		Age:            r.Age,
+		Health:         r.Health,
+		Energy:         r.Energy,
+		Weight:         r.Weight,
+		Died:           r.Died,
+		Picked:         r.Picked,
+		Genome:         genomes[r.Genome],
+		Brain:          r.Brain,
+		Bagage:         r.Bagage,
+		BagageSelected: r.BagageSelected,
+		BagageFullness: r.BagageFullness,
+		Bonds:          r.Bonds,
+		Position:       r.Position,
+		Rotation:       r.Rotation,
+	}, nil
+}
+
+// Cell descriptor of the files saved before wide energy and health
+type wCellDescriptorV2 struct {
+	Id           uint64
+	Generation   uint64
+	ParentsChain object.ParentsChain
+
 	Age    uint32
 	Health byte
 	Energy byte
@@ -32,8 +157,139 @@ type wCellDescriptor struct {
 	Genome cell.Genome
 	Brain  cell.Brain
 
-	Bagage [cell.BagageSize]uint64
+	Bagage         [cell.BagageSize]uint64
+	BagageSelected uint32
+	BagageFullness uint32
+
+	Bonds [cell.BondsCount]uint64
 
 	Position object.Position
 	Rotation object.Rotation
 }
+
+func (d wCellDescriptorV2) upgrade() wCellDescriptor {
+	return wCellDescriptor{
+		Id:             d.Id,
+		Generation:     d.Generation,
+		ParentsChain:   d.ParentsChain,
+		Age:            d.Age,
+		Health:         uint32(d.Health),
+		Energy:         uint32(d.Energy),
+		Weight:         d.Weight,
+		Died:           d.Died,
+		Picked:         d.Picked,
+		Genome:         d.Genome,
+		Brain:          d.Brain,
+		Bagage:         d.Bagage,
+		BagageSelected: d.BagageSelected,
+		BagageFullness: d.BagageFullness,
+		Bonds:          d.Bonds,
+		Position:       d.Position,
+		Rotation:       d.Rotation,
+	}
+}
+
+// Genome of the files saved before body traits
+type wGenomeV1 struct {
+	Hash uint64
+	Code [cell.GenomeLength]cell.Command
+}
+
+func (g wGenomeV1) upgrade() cell.Genome {
+	return cell.Genome{Hash: g.Hash, Code: g.Code, Traits: cell.BaseTraits()}
+}
+
+// Cell descriptor of the files saved before body traits
+type wCellDescriptorV1 struct {
+	Id           uint64
+	Generation   uint64
+	ParentsChain object.ParentsChain
+
+	Age    uint32
+	Health byte
+	Energy byte
+	Weight byte
+
+	Died   bool
+	Picked bool
+
+	Genome wGenomeV1
+	Brain  cell.Brain
+
+	Bagage         [cell.BagageSize]uint64
+	BagageSelected uint32
+	BagageFullness uint32
+
+	Bonds [cell.BondsCount]uint64
+
+	Position object.Position
+	Rotation object.Rotation
+}
+
+func (d wCellDescriptorV1) upgrade() wCellDescriptorV2 {
+	return wCellDescriptorV2{
+		Id:             d.Id,
+		Generation:     d.Generation,
+		ParentsChain:   d.ParentsChain,
+		Age:            d.Age,
+		Health:         d.Health,
+		Energy:         d.Energy,
+		Weight:         d.Weight,
+		Died:           d.Died,
+		Picked:         d.Picked,
+		Genome:         d.Genome.upgrade(),
+		Brain:          d.Brain,
+		Bagage:         d.Bagage,
+		BagageSelected: d.BagageSelected,
+		BagageFullness: d.BagageFullness,
+		Bonds:          d.Bonds,
+		Position:       d.Position,
+		Rotation:       d.Rotation,
+	}
+}
+
+// Cell descriptor of the files saved before versioning
+type wCellDescriptorV0 struct {
+	Id           uint64
+	Generation   uint64
+	ParentsChain object.ParentsChain
+
+	Age    uint32
+	Health byte
+	Energy byte
+	Weight byte
+
+	Died   bool
+	Picked bool
+
+	Genome wGenomeV1
+	Brain  cell.Brain
+
+	Bagage         [cell.BagageSize]uint64
+	BagageSelected uint32
+	BagageFullness uint32
+
+	Position object.Position
+	Rotation object.Rotation
+}
+
+func (d wCellDescriptorV0) upgrade() wCellDescriptorV1 {
+	return wCellDescriptorV1{
+		Id:             d.Id,
+		Generation:     d.Generation,
+		ParentsChain:   d.ParentsChain,
+		Age:            d.Age,
+		Health:         d.Health,
+		Energy:         d.Energy,
+		Weight:         d.Weight,
+		Died:           d.Died,
+		Picked:         d.Picked,
+		Genome:         d.Genome,
+		Brain:          d.Brain,
+		Bagage:         d.Bagage,
+		BagageSelected: d.BagageSelected,
+		BagageFullness: d.BagageFullness,
+		Position:       d.Position,
+		Rotation:       d.Rotation,
+	}
+}